@@ -2,22 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
-	"strconv"
+	"strings"
 	"syscall"
-	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"stockticker/internal/alerts"
 	"stockticker/internal/cache"
+	"stockticker/internal/config"
 	"stockticker/internal/controller"
+	"stockticker/internal/lifecycle"
 	"stockticker/internal/monitoring"
+	"stockticker/internal/portfolio"
 	"stockticker/internal/server"
 	"stockticker/internal/stockclient"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/pflag"
 
 	// Automatically configure GOMAXPROCS and GOMEMLIMIT in Docker containers
@@ -25,46 +30,8 @@ import (
 	_ "go.uber.org/automaxprocs"
 )
 
-const (
-	AppName                   = "stockticker"
-	MaxRequestDurationSeconds = 30
-
-	PprofServerHost = "127.0.0.1"
-	PprofServerPort = 6060
-
-	PromServerHost = "0.0.0.0"
-	PromServerPort = 9102
-)
-
-type hostPortType struct {
-	Host string
-	Port int
-}
-
-type cmdArgsType struct {
-	ListenAddr  hostPortType
-	EnableCache bool
-	RedisSrv    hostPortType
-}
-
-type envVars struct {
-	apiKey  string
-	symbol  string
-	numDays int
-}
-
 func init() {
-	logLevelStr, ok := os.LookupEnv("LOG_LEVEL")
-	if !ok {
-		logLevelStr = "info"
-	}
-	logLevel, err := log.ParseLevel(logLevelStr)
-	if err != nil {
-		logLevel = log.InfoLevel
-	}
-	log.SetLevel(logLevel)
-
-	_, err = memlimit.SetGoMemLimitWithOpts(
+	_, err := memlimit.SetGoMemLimitWithOpts(
 		memlimit.WithRatio(0.9),
 		memlimit.WithProvider(
 			memlimit.ApplyFallback(
@@ -79,123 +46,223 @@ func init() {
 	}
 }
 
-func parseCmdArgs() (*cmdArgsType, error) {
-	args := &cmdArgsType{}
-
-	flags := pflag.NewFlagSet(AppName, pflag.ExitOnError)
-	flags.SortFlags = false
+func main() {
+	// Config
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, pflag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Could not print configuration: %v", err)
+		}
+		return
+	}
+	log.SetLevel(cfg.Level())
+	log.SetFormatter(cfg.Formatter())
 
-	flags.StringVar(&args.ListenAddr.Host, "listen-ip", "0.0.0.0", "The IP address to listen on for HTTP requests")
-	flags.IntVar(&args.ListenAddr.Port, "listen-port", 8080, "The port to listen on for HTTP requests")
-	flags.BoolVar(&args.EnableCache, "enable-cache", false, "Enable/disable caching")
-	flags.StringVar(&args.RedisSrv.Host, "redis-host", "127.0.0.1", "The Redis host address to connect to")
-	flags.IntVar(&args.RedisSrv.Port, "redis-port", 6379, "The Redis port to connect to")
-	err := flags.Parse(os.Args[1:])
-	return args, err
+	if err := run(cfg); err != nil {
+		log.Errorf("Failed to run: %s", strings.ReplaceAll(err.Error(), "\n", "; "))
+		os.Exit(1)
+	}
 }
 
-func getEnvVars() (*envVars, error) {
-	var valid bool
-	var err error
-	ev := &envVars{}
-
-	ev.symbol, valid = os.LookupEnv("SYMBOL")
-	if !valid {
-		return nil, fmt.Errorf("SYMBOL not set")
-	}
+// run runs until SIGINT or SIGTERM, or until something fails. Whatever was set up is stopped before it returns
+func run(cfg *config.Config) error {
+	// Registered first so a signal received while setting up shuts down cleanly once everything has started
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	numDaysStr, valid := os.LookupEnv("NDAYS")
-	if !valid {
-		return nil, fmt.Errorf("NDAYS not set")
+	var group lifecycle.Group
+	server, err := setup(cfg, &group)
+	if err == nil {
+		// Bound before anything else runs so a port that's in use fails straight away
+		if err = server.Start(); err != nil {
+			err = fmt.Errorf("could not start HTTP server: %w", err)
+		}
 	}
-	ev.numDays, err = strconv.Atoi(numDaysStr)
 	if err != nil {
-		return nil, fmt.Errorf("NDAYS not a valid integer")
-	}
-	if ev.numDays <= 0 {
-		return nil, fmt.Errorf("NDAYS must be greater than zero")
+		return errors.Join(err, group.Stop(cfg.MaxRequestDuration))
 	}
+	log.Infof("HTTP server listening on %s", server.Addr())
+	// Added last so it's stopped first and in-flight requests can still use everything else
+	group.Add("HTTP server", func() error {
+		return <-server.Errors()
+	}, server.Stop)
+	return group.Run(ctx, cfg.MaxRequestDuration)
+}
 
-	ev.apiKey, valid = os.LookupEnv("APIKEY")
-	if !valid {
-		return nil, fmt.Errorf("APIKEY not set")
+// setup creates every component, adding all but the HTTP server to group
+func setup(cfg *config.Config, group *lifecycle.Group) (*server.Server, error) {
+	// Cache
+	var cacheClient cache.Client
+	if cfg.Cache.Enabled {
+		var err error
+		cacheClient, err = cache.NewRedisClient(cfg.Cache.Redis.Host, cfg.Cache.Redis.Port)
+		if err != nil {
+			return nil, fmt.Errorf("could not create Redis client: %w", err)
+		}
+	} else {
+		cacheClient, _ = cache.NewNullClient("", 0)
 	}
+	// Components are stopped in the reverse of the order they're added, so the cache is closed last
+	group.Add("cache client", nil, func(context.Context) error {
+		cacheClient.Close()
+		return nil
+	})
 
-	return ev, nil
-}
+	// Tracing, which is stopped after everything that records spans so they're all exported
+	if cfg.Monitoring.Tracing.Enabled {
+		tracer, err := monitoring.NewTracer(config.AppName, cfg.Monitoring.Tracing.Endpoint, cfg.Monitoring.Tracing.SampleRatio)
+		if err != nil {
+			return nil, fmt.Errorf("could not create tracer: %w", err)
+		}
+		log.Infof("Exporting traces to %s", cfg.Monitoring.Tracing.Endpoint)
+		group.Add("tracer", nil, tracer.Stop)
+	}
 
-func main() {
-	// Config
-	cmdArgs, err := parseCmdArgs()
+	// Stock
+	apiKeys, err := cfg.APIKeys()
+	if err != nil {
+		return nil, fmt.Errorf("could not load API keys: %w", err)
+	}
+	avClient, err := stockclient.NewAlphaVantageClient(apiKeys, cfg.Upstream.DailyQuotaPerKey)
 	if err != nil {
-		log.Fatalf("Could not parse command line args: %s", err)
+		return nil, fmt.Errorf("could not create an Alpha Vantage client: %w", err)
 	}
+	prometheus.MustRegister(avClient.KeyMetrics())
 
-	envVars, err := getEnvVars()
+	stockCtrler, err := controller.NewStockController(avClient, cacheClient, cfg.Symbol, cfg.NumDays,
+		controller.WithUpstreamRateLimit(cfg.Upstream.RequestsPerMinute), controller.WithFXProvider(avClient),
+		controller.WithCacheTimeout(cfg.Cache.Timeout), controller.WithCacheTTLs(cacheTTLs(cfg)))
 	if err != nil {
-		log.Fatalf("Could not get required environment variables: %s", err)
+		return nil, fmt.Errorf("could not create stock controller: %w", err)
 	}
 
-	// Pprof
-	pprofServer, err := monitoring.NewPprofServer(PprofServerHost, PprofServerPort)
+	// Alerts
+	alertRules, err := loadAlertRules(cfg.Alerts.RulesFile)
 	if err != nil {
-		log.Fatalf("Could not create pprof server: %v", err)
+		return nil, fmt.Errorf("could not load alert rules: %w", err)
 	}
-	log.Printf("Pprof HTTP server listening on %s:%d", PprofServerHost, PprofServerPort)
-	err = pprofServer.Listen()
+	notifier, err := alerts.NewWebhookNotifier(cfg.Alerts.Webhooks, string(cfg.Alerts.WebhookSecret))
 	if err != nil {
-		log.Fatalf("Could not start pprof server: %v", err)
+		return nil, fmt.Errorf("could not create webhook notifier: %w", err)
 	}
+	group.Add("alert notifier", notifier.Run, notifier.Stop)
 
-	// Prometheus
-	promServer, err := monitoring.NewPrometheusServer(PromServerHost, PromServerPort)
+	alertCtrler, err := controller.NewAlertController(stockCtrler, cacheClient, notifier, alertRules, cfg.Alerts.CheckInterval)
 	if err != nil {
-		log.Fatalf("Could not create Prometheus server: %v", err)
+		return nil, fmt.Errorf("could not create alert controller: %w", err)
 	}
-	log.Infof("Prometheus HTTP server listening on %s:%d", PromServerHost, PromServerPort)
-	err = promServer.Listen()
+	group.AddContext("alert evaluator", alertCtrler.Run)
+
+	watchlistCtrler, err := controller.NewWatchlistController(stockCtrler, cacheClient, cfg.Watchlists, cfg.Upstream.MaxConcurrentFetches)
 	if err != nil {
-		log.Fatalf("Could not start Prometheus server: %v", err)
+		return nil, fmt.Errorf("could not create watchlist controller: %w", err)
 	}
 
-	// Cache
-	var cacheClient cache.Client
-	if cmdArgs.EnableCache {
-		cacheClient, err = cache.NewRedisClient(cmdArgs.RedisSrv.Host, cmdArgs.RedisSrv.Port)
+	positions := []portfolio.Position{}
+	if cfg.PortfolioFile != "" {
+		positions, err = portfolio.LoadPositions(cfg.PortfolioFile)
 		if err != nil {
-			log.Fatalf("Could not create Redis client: %v", err)
+			return nil, fmt.Errorf("could not load portfolio: %w", err)
 		}
-	} else {
-		cacheClient, _ = cache.NewNullClient("", 0)
 	}
-	defer cacheClient.Close()
+	portfolioCtrler, err := controller.NewPortfolioController(stockCtrler, positions, cfg.Upstream.MaxConcurrentFetches)
+	if err != nil {
+		return nil, fmt.Errorf("could not create portfolio controller: %w", err)
+	}
 
-	// Stock
-	av_client, err := stockclient.NewAlphaVantageClient(envVars.apiKey)
+	comparisonCtrler, err := controller.NewComparisonController(stockCtrler, cfg.Upstream.MaxConcurrentFetches)
 	if err != nil {
-		log.Fatalf("Could not create a Alpha Vantage client: %v", err)
+		return nil, fmt.Errorf("could not create comparison controller: %w", err)
 	}
 
-	stockCtrler, err := controller.NewStockController(av_client, cacheClient, envVars.symbol, envVars.numDays)
+	// Quote streaming
+	quotePoller, err := controller.NewQuotePoller(stockCtrler, cfg.Streams.QuotePollInterval, cfg.Streams.MaxSymbols)
 	if err != nil {
-		log.Fatalf("Could not create stock contorller: %v", err)
+		return nil, fmt.Errorf("could not create quote poller: %w", err)
+	}
+	group.AddContext("quote poller", quotePoller.Run)
+
+	// Config reloading
+	watcher := config.NewWatcher(cfg, os.Args[1:], os.LookupEnv, func(old, new *config.Config) error {
+		apiKeys, err := new.APIKeys()
+		if err != nil {
+			return err
+		}
+		alertRules, err := loadAlertRules(new.Alerts.RulesFile)
+		if err != nil {
+			return fmt.Errorf("could not load alert rules: %w", err)
+		}
+		if err := watchlistCtrler.SetConfigured(new.Watchlists); err != nil {
+			return err
+		}
+		if err := alertCtrler.SetConfigured(alertRules); err != nil {
+			// The old watchlists were valid, so restoring them can't fail
+			_ = watchlistCtrler.SetConfigured(old.Watchlists)
+			return err
+		}
+		stockCtrler.Reconfigure(new.Symbol, new.NumDays, cacheTTLs(new))
+		log.SetLevel(new.Level())
+		log.SetFormatter(new.Formatter())
+		// The keys were validated when they were loaded, so this can't fail
+		return avClient.SetAPIKeys(apiKeys)
+	})
+	group.AddContext("config watcher", watcher.Run)
+
+	// Pprof
+	if cfg.Monitoring.Pprof.Enabled {
+		pprofServer, err := monitoring.NewPprofServer(cfg.Monitoring.Pprof.Host, cfg.Monitoring.Pprof.Port)
+		if err != nil {
+			return nil, fmt.Errorf("could not create pprof server: %w", err)
+		}
+		group.Add("pprof server", pprofServer.ListenAndServe, pprofServer.Stop)
+	}
+
+	// Prometheus
+	if cfg.Monitoring.Prometheus.Enabled {
+		promServer, err := monitoring.NewPrometheusServer(cfg.Monitoring.Prometheus.Host, cfg.Monitoring.Prometheus.Port)
+		if err != nil {
+			return nil, fmt.Errorf("could not create Prometheus server: %w", err)
+		}
+		group.Add("Prometheus server", promServer.ListenAndServe, promServer.Stop)
 	}
 
 	// HTTP server
-	server, err := server.NewServer(stockCtrler, cmdArgs.ListenAddr.Host, cmdArgs.ListenAddr.Port)
+	server, err := server.NewServer(server.Config{
+		StockCtrler:      stockCtrler,
+		WatchlistCtrler:  watchlistCtrler,
+		AlertCtrler:      alertCtrler,
+		PortfolioCtrler:  portfolioCtrler,
+		ComparisonCtrler: comparisonCtrler,
+		QuotePoller:      quotePoller,
+		Host:             cfg.Listen.Host,
+		Port:             cfg.Listen.Port,
+		MaxStreams:       cfg.Streams.Max,
+		ServeMetrics:     cfg.Monitoring.MetricsOnMainListener,
+		APIToken:         string(cfg.APIToken),
+	})
 	if err != nil {
-		log.Fatalf("Could not create server: %v", err)
+		return nil, fmt.Errorf("could not create server: %w", err)
 	}
-	log.Infof("HTTP server listening on %s:%d", cmdArgs.ListenAddr.Host, cmdArgs.ListenAddr.Port)
-	ctx := context.Background()
-	server.Start(ctx)
+	return server, nil
+}
 
-	// Graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	err = server.Stop(ctx, time.Duration(MaxRequestDurationSeconds*time.Second))
-	if err != nil {
-		log.Errorf("Failed to gracefully shut down server: %v", err)
+func loadAlertRules(path string) ([]alerts.Rule, error) {
+	if path == "" {
+		return []alerts.Rule{}, nil
+	}
+	return alerts.LoadRules(path)
+}
+
+func cacheTTLs(cfg *config.Config) controller.CacheTTLs {
+	return controller.CacheTTLs{
+		Quote:        cfg.Cache.TTLs.Quote,
+		SymbolSearch: cfg.Cache.TTLs.SymbolSearch,
+		Overview:     cfg.Cache.TTLs.Overview,
 	}
 }
@@ -0,0 +1,16 @@
+package alerts
+
+import "stockticker/internal/datafile"
+
+type rulesFile struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadRules reads rules from a YAML, JSON or TOML file with a top level rules list
+func LoadRules(path string) ([]Rule, error) {
+	file := rulesFile{}
+	if err := datafile.Load(path, &file); err != nil {
+		return nil, err
+	}
+	return file.Rules, nil
+}
@@ -0,0 +1,18 @@
+package alerts
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	webhookDeliveries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "stockticker",
+			Subsystem: "alerts",
+			Name:      "webhook_deliveries_total",
+			Help:      "Number of alert webhook deliveries by result",
+		},
+		[]string{"result"},
+	)
+)
@@ -0,0 +1,131 @@
+package alerts
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"stockticker/internal/stockclient"
+	"time"
+)
+
+type RuleType string
+
+const (
+	// Above triggers when the close crosses above Threshold
+	Above RuleType = "above"
+	// Below triggers when the close crosses below Threshold
+	Below RuleType = "below"
+	// PercentMove triggers when the close moves by at least Threshold percent in either direction in a day
+	PercentMove RuleType = "percent_move"
+	// MACross triggers when the close crosses its Window day moving average in either direction
+	MACross RuleType = "ma_cross"
+
+	maxMAWindow = 200
+)
+
+var (
+	ErrInvalidRule = errors.New("invalid alert rule")
+
+	ruleIDRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+)
+
+type Rule struct {
+	ID     string   `json:"id" yaml:"id"`
+	Symbol string   `json:"symbol" yaml:"symbol"`
+	Type   RuleType `json:"type" yaml:"type"`
+	// A price for above and below, or a percentage for percent_move
+	Threshold float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`
+	// The number of days in the moving average for ma_cross
+	Window int `json:"window,omitempty" yaml:"window,omitempty"`
+}
+
+// Trigger describes why a rule fired
+type Trigger struct {
+	Date    time.Time
+	Close   float64
+	Message string
+}
+
+// Validate checks the rule is usable. The symbol is validated separately as it's normalised by the caller
+func (r *Rule) Validate() error {
+	if !ruleIDRegexp.MatchString(r.ID) {
+		return fmt.Errorf("%w: id '%s' must be 1-64 lower case letters, digits, underscores or hyphens", ErrInvalidRule, r.ID)
+	}
+
+	switch r.Type {
+	case Above, Below:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("%w: '%s' threshold must be a price greater than zero", ErrInvalidRule, r.ID)
+		}
+	case PercentMove:
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("%w: '%s' threshold must be a percentage between 0 and 100", ErrInvalidRule, r.ID)
+		}
+	case MACross:
+		if r.Window < 2 || r.Window > maxMAWindow {
+			return fmt.Errorf("%w: '%s' window must be between 2 and %d days", ErrInvalidRule, r.ID, maxMAWindow)
+		}
+	default:
+		return fmt.Errorf("%w: '%s' type must be one of %s, %s, %s or %s", ErrInvalidRule, r.ID, Above, Below, PercentMove, MACross)
+	}
+	return nil
+}
+
+// Evaluate checks whether the rule fired on the most recent day of dailyData, which must be sorted newest first.
+// nil is returned if it didn't or there isn't enough data to tell
+func (r *Rule) Evaluate(dailyData []*stockclient.DayData) *Trigger {
+	if len(dailyData) < 2 {
+		return nil
+	}
+	latest, prev := dailyData[0], dailyData[1]
+
+	switch r.Type {
+	case Above:
+		if prev.Close <= r.Threshold && latest.Close > r.Threshold {
+			return r.trigger(latest, fmt.Sprintf("%s closed at %.2f, crossing above %.2f", r.Symbol, latest.Close, r.Threshold))
+		}
+	case Below:
+		if prev.Close >= r.Threshold && latest.Close < r.Threshold {
+			return r.trigger(latest, fmt.Sprintf("%s closed at %.2f, crossing below %.2f", r.Symbol, latest.Close, r.Threshold))
+		}
+	case PercentMove:
+		if prev.Close == 0 {
+			return nil
+		}
+		change := (latest.Close - prev.Close) / prev.Close * 100
+		if math.Abs(change) >= r.Threshold {
+			return r.trigger(latest, fmt.Sprintf("%s closed at %.2f, a move of %+.2f%%", r.Symbol, latest.Close, change))
+		}
+	case MACross:
+		// Moving averages are needed for both the latest and previous day
+		if len(dailyData) < r.Window+1 {
+			return nil
+		}
+		ma := movingAverage(dailyData[:r.Window])
+		prevMA := movingAverage(dailyData[1 : r.Window+1])
+		if prev.Close <= prevMA && latest.Close > ma {
+			return r.trigger(latest, fmt.Sprintf("%s closed at %.2f, crossing above its %d day moving average of %.2f", r.Symbol, latest.Close, r.Window, ma))
+		}
+		if prev.Close >= prevMA && latest.Close < ma {
+			return r.trigger(latest, fmt.Sprintf("%s closed at %.2f, crossing below its %d day moving average of %.2f", r.Symbol, latest.Close, r.Window, ma))
+		}
+	}
+	return nil
+}
+
+func (r *Rule) trigger(dayData *stockclient.DayData, msg string) *Trigger {
+	return &Trigger{
+		Date:    dayData.Date,
+		Close:   dayData.Close,
+		Message: msg,
+	}
+}
+
+func movingAverage(dailyData []*stockclient.DayData) float64 {
+	sum := float64(0)
+	for _, dayData := range dailyData {
+		sum += dayData.Close
+	}
+	return sum / float64(len(dailyData))
+}
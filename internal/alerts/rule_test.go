@@ -0,0 +1,84 @@
+package alerts
+
+import (
+	"stockticker/internal/stockclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// closes returns daily data sorted newest first for the given closes, which are oldest first
+func closes(prices ...float64) []*stockclient.DayData {
+	dailyData := []*stockclient.DayData{}
+	date := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	for _, price := range prices {
+		dailyData = append([]*stockclient.DayData{{Date: date, Close: price}}, dailyData...)
+		date = date.AddDate(0, 0, 1)
+	}
+	return dailyData
+}
+
+func TestValidate(t *testing.T) {
+	valid := []Rule{
+		{ID: "msft-above", Type: Above, Threshold: 400},
+		{ID: "msft_below", Type: Below, Threshold: 0.5},
+		{ID: "move", Type: PercentMove, Threshold: 5},
+		{ID: "cross", Type: MACross, Window: 50},
+	}
+	for _, rule := range valid {
+		require.NoError(t, rule.Validate(), rule.ID)
+	}
+
+	invalid := []Rule{
+		{ID: "MSFT", Type: Above, Threshold: 400},
+		{ID: "", Type: Above, Threshold: 400},
+		{ID: "above", Type: Above},
+		{ID: "below", Type: Below, Threshold: -1},
+		{ID: "move", Type: PercentMove, Threshold: 101},
+		{ID: "cross", Type: MACross, Window: 1},
+		{ID: "cross", Type: MACross, Window: maxMAWindow + 1},
+		{ID: "unknown", Type: "sideways", Threshold: 1},
+	}
+	for _, rule := range invalid {
+		require.ErrorIs(t, rule.Validate(), ErrInvalidRule, rule.ID)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      Rule
+		dailyData []*stockclient.DayData
+		fires     bool
+	}{
+		{"Above crossing", Rule{Type: Above, Threshold: 100}, closes(99, 101), true},
+		{"Above already above", Rule{Type: Above, Threshold: 100}, closes(101, 102), false},
+		{"Above from threshold", Rule{Type: Above, Threshold: 100}, closes(100, 100.01), true},
+		{"Below crossing", Rule{Type: Below, Threshold: 100}, closes(101, 99), true},
+		{"Below already below", Rule{Type: Below, Threshold: 100}, closes(99, 98), false},
+		{"Percent move up", Rule{Type: PercentMove, Threshold: 5}, closes(100, 105), true},
+		{"Percent move down", Rule{Type: PercentMove, Threshold: 5}, closes(100, 94), true},
+		{"Percent move too small", Rule{Type: PercentMove, Threshold: 5}, closes(100, 104), false},
+		{"MA cross above", Rule{Type: MACross, Window: 3}, closes(10, 10, 10, 9, 12), true},
+		{"MA cross below", Rule{Type: MACross, Window: 3}, closes(10, 10, 10, 11, 8), true},
+		{"MA no cross", Rule{Type: MACross, Window: 3}, closes(10, 10, 10, 11, 12), false},
+		{"MA not enough data", Rule{Type: MACross, Window: 3}, closes(10, 9, 12), false},
+		{"Single day", Rule{Type: Above, Threshold: 100}, closes(101), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.rule.Symbol = "MSFT"
+			trigger := test.rule.Evaluate(test.dailyData)
+			if !test.fires {
+				require.Nil(t, trigger)
+				return
+			}
+			require.NotNil(t, trigger)
+			require.Equal(t, test.dailyData[0].Date, trigger.Date)
+			require.Equal(t, test.dailyData[0].Close, trigger.Close)
+			require.Contains(t, trigger.Message, "MSFT")
+		})
+	}
+}
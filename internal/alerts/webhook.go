@@ -0,0 +1,225 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"stockticker/internal/logging"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Stockticker-Signature"
+	TimestampHeader = "X-Stockticker-Timestamp"
+
+	webhookQueueSize   = 100
+	webhookMaxAttempts = 5
+	webhookMaxBackoff  = 30 * time.Second
+)
+
+// Alert is the payload delivered to webhooks
+type Alert struct {
+	// Stable across retries so receivers can de-duplicate deliveries
+	ID          string    `json:"id"`
+	Rule        Rule      `json:"rule"`
+	Symbol      string    `json:"symbol"`
+	Date        time.Time `json:"date"`
+	Close       float64   `json:"close"`
+	Message     string    `json:"message"`
+	TriggeredAt time.Time `json:"triggeredAt"`
+}
+
+// queuedAlert is an alert waiting to be delivered along with the context it fired in
+type queuedAlert struct {
+	ctx   context.Context
+	alert *Alert
+}
+
+// WebhookNotifier delivers alerts to webhooks in the background, retrying failed deliveries with exponential backoff
+type WebhookNotifier struct {
+	urls           []string
+	secret         []byte
+	httpClient     *http.Client
+	queue          chan queuedAlert
+	initialBackoff time.Duration
+
+	// Cancelled when the deadline for delivering the queue on shutdown passes
+	deliveryCtx      context.Context
+	cancelDeliveries context.CancelFunc
+	stopping         chan struct{}
+	stopOnce         sync.Once
+}
+
+func NewWebhookNotifier(urls []string, secret string) (*WebhookNotifier, error) {
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL '%s'", u)
+		}
+	}
+	if len(urls) > 0 && secret == "" {
+		return nil, fmt.Errorf("a secret is required to sign webhook payloads")
+	}
+
+	deliveryCtx, cancelDeliveries := context.WithCancel(context.Background())
+	return &WebhookNotifier{
+		urls:   urls,
+		secret: []byte(secret),
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		queue:            make(chan queuedAlert, webhookQueueSize),
+		initialBackoff:   time.Second,
+		deliveryCtx:      deliveryCtx,
+		cancelDeliveries: cancelDeliveries,
+		stopping:         make(chan struct{}),
+	}, nil
+}
+
+// Notify queues alert, which fired in ctx, for delivery. Alerts are dropped if the queue is full so callers are never
+// blocked
+func (n *WebhookNotifier) Notify(ctx context.Context, alert *Alert) {
+	if len(n.urls) == 0 {
+		return
+	}
+
+	select {
+	case n.queue <- queuedAlert{ctx: ctx, alert: alert}:
+	default:
+		webhookDeliveries.WithLabelValues("dropped").Inc()
+		logging.FromContext(ctx).Errorf("Dropping alert %s as the webhook queue is full", alert.ID)
+	}
+}
+
+// Run delivers queued alerts until Stop is called, then delivers whatever is left in the queue before returning
+func (n *WebhookNotifier) Run() error {
+	for {
+		select {
+		case <-n.stopping:
+			n.drain()
+			return nil
+		case queued := <-n.queue:
+			n.send(queued)
+		}
+	}
+}
+
+// Stop makes Run return once the queue is delivered. Deliveries still in progress when ctx is done are abandoned
+// and the rest of the queue is dropped
+func (n *WebhookNotifier) Stop(ctx context.Context) error {
+	n.stopOnce.Do(func() {
+		close(n.stopping)
+		context.AfterFunc(ctx, n.cancelDeliveries)
+	})
+	return nil
+}
+
+// drain delivers the alerts left in the queue
+func (n *WebhookNotifier) drain() {
+	for {
+		select {
+		case queued := <-n.queue:
+			if n.deliveryCtx.Err() != nil {
+				webhookDeliveries.WithLabelValues("dropped").Inc()
+				logging.FromContext(queued.ctx).Errorf("Dropping alert %s as shutting down took too long", queued.alert.ID)
+				continue
+			}
+			n.send(queued)
+		default:
+			return
+		}
+	}
+}
+
+// send delivers a queued alert to every webhook
+func (n *WebhookNotifier) send(queued queuedAlert) {
+	// Deliveries keep the values of the context the alert fired in, so their logs can be correlated with it, but are
+	// only cancelled on shutdown
+	ctx, cancel := context.WithCancel(context.WithoutCancel(queued.ctx))
+	defer cancel()
+	stop := context.AfterFunc(n.deliveryCtx, cancel)
+	defer stop()
+
+	logger := logging.FromContext(ctx)
+	body, err := json.Marshal(queued.alert)
+	if err != nil {
+		logger.Errorf("Failed to encode alert %s: %v", queued.alert.ID, err)
+		return
+	}
+	for _, u := range n.urls {
+		if err := n.deliver(ctx, u, body); err != nil {
+			webhookDeliveries.WithLabelValues("failed").Inc()
+			logger.Errorf("Failed to deliver alert %s: %v", queued.alert.ID, err)
+		} else {
+			webhookDeliveries.WithLabelValues("delivered").Inc()
+		}
+	}
+}
+
+// deliver posts body to u, retrying on network errors, rate limiting and server errors
+func (n *WebhookNotifier) deliver(ctx context.Context, u string, body []byte) error {
+	backoff := n.initialBackoff
+	var err error
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		var retry bool
+		retry, err = n.post(ctx, u, body)
+		if err == nil || !retry {
+			return err
+		}
+		if attempt == webhookMaxAttempts {
+			break
+		}
+
+		logging.FromContext(ctx).Warnf("Webhook delivery attempt %d failed, retrying in %v: %v", attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, webhookMaxBackoff)
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", webhookMaxAttempts, err)
+}
+
+// post makes a single delivery attempt, returning whether a failure is worth retrying
+func (n *WebhookNotifier) post(ctx context.Context, u string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("building http request failed: %w", err)
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(n.secret, timestamp, body))
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+// Sign returns the signature sent with a webhook payload. The timestamp is included so receivers can reject replays
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
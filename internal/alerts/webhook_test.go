@@ -0,0 +1,162 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"stockticker/internal/logging"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWebhookNotifier(t *testing.T) {
+	_, err := NewWebhookNotifier(nil, "")
+	require.NoError(t, err)
+
+	_, err = NewWebhookNotifier([]string{"https://example.com/hook"}, "")
+	require.Error(t, err)
+
+	_, err = NewWebhookNotifier([]string{"example.com/hook"}, "secret")
+	require.Error(t, err)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	alert := &Alert{
+		ID:      "msft-above:2024-10-18",
+		Rule:    Rule{ID: "msft-above", Symbol: "MSFT", Type: Above, Threshold: 400},
+		Symbol:  "MSFT",
+		Date:    time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC),
+		Close:   418.16,
+		Message: "MSFT closed at 418.16, crossing above 400.00",
+	}
+
+	newNotifier := func(t *testing.T, handler http.HandlerFunc) *WebhookNotifier {
+		ts := httptest.NewServer(handler)
+		t.Cleanup(ts.Close)
+		notifier, err := NewWebhookNotifier([]string{ts.URL}, "secret")
+		require.NoError(t, err)
+		notifier.initialBackoff = time.Millisecond
+		return notifier
+	}
+
+	t.Run("Payloads are signed", func(t *testing.T) {
+		received := make(chan *Alert, 1)
+		notifier := newNotifier(t, func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, Sign([]byte("secret"), r.Header.Get(TimestampHeader), body), r.Header.Get(SignatureHeader))
+
+			var got Alert
+			require.NoError(t, json.Unmarshal(body, &got))
+			received <- &got
+		})
+
+		go notifier.Run()
+		defer notifier.Stop(context.Background())
+		notifier.Notify(context.Background(), alert)
+
+		select {
+		case got := <-received:
+			require.Equal(t, alert.ID, got.ID)
+			require.Equal(t, alert.Rule, got.Rule)
+		case <-time.After(5 * time.Second):
+			t.Fatal("alert wasn't delivered")
+		}
+	})
+
+	t.Run("Queued alerts are delivered on shutdown", func(t *testing.T) {
+		var delivered atomic.Int32
+		notifier := newNotifier(t, func(w http.ResponseWriter, r *http.Request) {
+			delivered.Add(1)
+		})
+		notifier.Notify(context.Background(), alert)
+		notifier.Notify(context.Background(), alert)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, notifier.Stop(ctx))
+		require.NoError(t, notifier.Run())
+		require.Equal(t, int32(2), delivered.Load())
+	})
+
+	t.Run("Queued alerts are dropped after the shutdown deadline", func(t *testing.T) {
+		notifier := newNotifier(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+		notifier.initialBackoff = time.Minute
+		notifier.Notify(context.Background(), alert)
+		notifier.Notify(context.Background(), alert)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		require.NoError(t, notifier.Stop(ctx))
+		start := time.Now()
+		require.NoError(t, notifier.Run())
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("Failures are logged with the context the alert fired in", func(t *testing.T) {
+		var buf bytes.Buffer
+		out, formatter := log.StandardLogger().Out, log.StandardLogger().Formatter
+		log.SetOutput(&buf)
+		log.SetFormatter(&log.JSONFormatter{})
+		t.Cleanup(func() {
+			log.SetOutput(out)
+			log.SetFormatter(formatter)
+		})
+
+		notifier := newNotifier(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		})
+		notifier.Notify(logging.WithRequestID(context.Background(), "abc123"), alert)
+		require.NoError(t, notifier.Stop(context.Background()))
+		require.NoError(t, notifier.Run())
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		require.Equal(t, "abc123", entry["request_id"])
+		require.Contains(t, entry["msg"], "Failed to deliver alert "+alert.ID)
+	})
+
+	t.Run("Server errors are retried", func(t *testing.T) {
+		var attempts atomic.Int32
+		notifier := newNotifier(t, func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+
+		require.NoError(t, notifier.deliver(context.Background(), notifier.urls[0], []byte("{}")))
+		require.Equal(t, int32(3), attempts.Load())
+	})
+
+	t.Run("Delivery gives up after the maximum attempts", func(t *testing.T) {
+		var attempts atomic.Int32
+		notifier := newNotifier(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		require.Error(t, notifier.deliver(context.Background(), notifier.urls[0], []byte("{}")))
+		require.Equal(t, int32(webhookMaxAttempts), attempts.Load())
+	})
+
+	t.Run("Client errors aren't retried", func(t *testing.T) {
+		var attempts atomic.Int32
+		notifier := newNotifier(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		require.Error(t, notifier.deliver(context.Background(), notifier.urls[0], []byte("{}")))
+		require.Equal(t, int32(1), attempts.Load())
+	})
+}
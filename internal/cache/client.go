@@ -8,5 +8,8 @@ import (
 type Client interface {
 	Get(ctx context.Context, key string) (string, error)
 	Set(ctx context.Context, key, val string, ttl time.Duration) error
+	// Update atomically replaces the value of key with the result of fn, which is passed the current value or an empty
+	// string if there isn't one. fn may be called more than once if the value is changed concurrently
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(val string) (string, error)) error
 	Close()
 }
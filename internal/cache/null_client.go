@@ -19,4 +19,9 @@ func (n *NullClient) Set(ctx context.Context, key, val string, ttl time.Duration
 	return nil
 }
 
+func (n *NullClient) Update(ctx context.Context, key string, ttl time.Duration, fn func(val string) (string, error)) error {
+	_, err := fn("")
+	return err
+}
+
 func (n *NullClient) Close() {}
@@ -2,12 +2,17 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
+// Number of times an update is attempted when the value keeps changing concurrently
+const maxUpdateAttempts = 10
+
 type RedisClient struct {
 	client *redis.Client
 }
@@ -18,6 +23,12 @@ func NewRedisClient(ip string, port int) (*RedisClient, error) {
 		ContextTimeoutEnabled: true,
 	})
 
+	// Each command is traced as a child of the caller's span. Arguments aren't recorded as values can be large
+	if err := redisotel.InstrumentTracing(client, redisotel.WithDBStatement(false)); err != nil {
+		client.Close()
+		return nil, err
+	}
+
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		client.Close()
@@ -42,6 +53,33 @@ func (rs *RedisClient) Set(ctx context.Context, key, val string, ttl time.Durati
 	return rs.client.Set(ctx, key, val, ttl).Err()
 }
 
+// Update uses optimistic locking, retrying if key is changed between reading and writing it
+func (rs *RedisClient) Update(ctx context.Context, key string, ttl time.Duration, fn func(val string) (string, error)) error {
+	update := func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return err
+		}
+		val, err = fn(val)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, val, ttl)
+			return nil
+		})
+		return err
+	}
+
+	for range maxUpdateAttempts {
+		err := rs.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("%s was changed concurrently %d times in a row", key, maxUpdateAttempts)
+}
+
 func (rs *RedisClient) Close() {
 	rs.client.Close()
 }
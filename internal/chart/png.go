@@ -0,0 +1,122 @@
+package chart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"stockticker/internal/stockclient"
+)
+
+const (
+	sparklineStroke = 1.5
+	// Radius of the marker on the latest close
+	sparklineMarker = 2.0
+)
+
+var (
+	sparklineUp   = color.NRGBA{R: 0x1a, G: 0x7f, B: 0x37, A: 0xff}
+	sparklineDown = color.NRGBA{R: 0xcf, G: 0x22, B: 0x2e, A: 0xff}
+)
+
+type point struct {
+	x, y float64
+}
+
+// SparklinePNG renders the closes of the most recent days of dailyData, which is ordered newest first, as an
+// anti-aliased line on a transparent width by height PNG. The line is green if the latest close is at least the
+// earliest and red otherwise
+func SparklinePNG(dailyData []*stockclient.DayData, days int, width, height int) ([]byte, error) {
+	days = min(days, len(dailyData))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	if days > 0 {
+		closes := make([]float64, days)
+		for i := range days {
+			closes[i] = dailyData[days-1-i].Close
+		}
+
+		lineColour := sparklineUp
+		if closes[days-1] < closes[0] {
+			lineColour = sparklineDown
+		}
+
+		coverage := make([]float64, width*height)
+		points := sparklinePoints(closes, width, height)
+		for i := 1; i < len(points); i++ {
+			cover(coverage, width, height, points[i-1], points[i], sparklineStroke/2)
+		}
+		last := points[len(points)-1]
+		cover(coverage, width, height, last, last, sparklineMarker)
+
+		for i, c := range coverage {
+			if c > 0 {
+				colour := lineColour
+				colour.A = uint8(math.Round(c * 0xff))
+				img.SetNRGBA(i%width, i/width, colour)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sparklinePoints scales closes, which are oldest first, to fill the image leaving room for the line's width
+func sparklinePoints(closes []float64, width, height int) []point {
+	inset := sparklineMarker + 0.5
+	plotWidth := float64(width) - 2*inset
+	plotHeight := float64(height) - 2*inset
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, price := range closes {
+		low = min(low, price)
+		high = max(high, price)
+	}
+
+	points := make([]point, len(closes))
+	for i, price := range closes {
+		p := point{x: inset + plotWidth/2, y: inset + plotHeight/2}
+		if len(closes) > 1 {
+			p.x = inset + float64(i)/float64(len(closes)-1)*plotWidth
+		}
+		// Flat lines are drawn through the middle
+		if high > low {
+			p.y = inset + (high-price)/(high-low)*plotHeight
+		}
+		points[i] = p
+	}
+	return points
+}
+
+// cover records how much of each pixel is within radius of the segment from a to b, keeping the highest coverage
+// where segments overlap
+func cover(coverage []float64, width, height int, a, b point, radius float64) {
+	minX := max(int(math.Floor(min(a.x, b.x)-radius-1)), 0)
+	maxX := min(int(math.Ceil(max(a.x, b.x)+radius+1)), width-1)
+	minY := max(int(math.Floor(min(a.y, b.y)-radius-1)), 0)
+	maxY := min(int(math.Ceil(max(a.y, b.y)+radius+1)), height-1)
+
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			// Distance from the pixel's centre, with a one pixel wide falloff for anti-aliasing
+			d := distanceToSegment(point{float64(x) + 0.5, float64(y) + 0.5}, a, b)
+			c := math.Min(math.Max(radius+0.5-d, 0), 1)
+			i := y*width + x
+			coverage[i] = max(coverage[i], c)
+		}
+	}
+}
+
+func distanceToSegment(p, a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := float64(0)
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Min(math.Max(((p.x-a.x)*dx+(p.y-a.y)*dy)/lengthSq, 0), 1)
+	}
+	return math.Hypot(p.x-(a.x+t*dx), p.y-(a.y+t*dy))
+}
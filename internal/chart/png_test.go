@@ -0,0 +1,61 @@
+package chart
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, data []byte) image.Image {
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func TestSparklinePNG(t *testing.T) {
+	t.Run("Rising closes are green", func(t *testing.T) {
+		data, err := SparklinePNG(days(0, 1, 3, 2, 4), 10, 100, 20)
+		require.NoError(t, err)
+		img := decode(t, data)
+		require.Equal(t, image.Rect(0, 0, 100, 20), img.Bounds())
+
+		// The line starts at the bottom left and ends at the top right, leaving the opposite corners transparent
+		_, _, _, a := img.At(3, 16).RGBA()
+		require.NotZero(t, a)
+		r, g, _, a := img.At(96, 3).RGBA()
+		require.NotZero(t, a)
+		require.Greater(t, g, r)
+		_, _, _, a = img.At(96, 16).RGBA()
+		require.Zero(t, a)
+		_, _, _, a = img.At(3, 3).RGBA()
+		require.Zero(t, a)
+	})
+
+	t.Run("Falling closes are red", func(t *testing.T) {
+		data, err := SparklinePNG(days(0, 4, 2, 3, 1), 10, 100, 20)
+		require.NoError(t, err)
+		r, g, _, a := decode(t, data).At(96, 16).RGBA()
+		require.NotZero(t, a)
+		require.Greater(t, r, g)
+	})
+
+	t.Run("Only the most recent days are drawn", func(t *testing.T) {
+		// The spike is outside the last 2 days so the line is flat through the middle
+		data, err := SparklinePNG(days(0, 100, 1, 1), 2, 100, 20)
+		require.NoError(t, err)
+		img := decode(t, data)
+		_, _, _, a := img.At(50, 10).RGBA()
+		require.NotZero(t, a)
+		_, _, _, a = img.At(50, 3).RGBA()
+		require.Zero(t, a)
+	})
+
+	t.Run("No data", func(t *testing.T) {
+		data, err := SparklinePNG(nil, 10, 16, 8)
+		require.NoError(t, err)
+		require.Equal(t, image.Rect(0, 0, 16, 8), decode(t, data).Bounds())
+	})
+}
@@ -0,0 +1,240 @@
+package chart
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"slices"
+	"stockticker/internal/stockclient"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	width  = 800
+	height = 400
+	// Space for the price axis labels on the left and date labels at the bottom
+	marginLeft   = 64
+	marginRight  = 16
+	marginTop    = 24
+	marginBottom = 24
+	// Share of the plot height used by the volume bars
+	volumeShare = 0.2
+	gridLines   = 5
+
+	MaxMovingAverages    = 3
+	MaxMovingAverageDays = 200
+
+	closeColour  = "#0969da"
+	volumeColour = "#8c959f"
+)
+
+var (
+	ErrInvalidMovingAverage = errors.New("invalid moving average")
+
+	movingAverageColours = []string{"#bf8700", "#8250df", "#1a7f37"}
+)
+
+// ValidateMovingAverages checks the number of days of each moving average is usable
+func ValidateMovingAverages(movingAverages []int) error {
+	if len(movingAverages) > MaxMovingAverages {
+		return fmt.Errorf("%w: at most %d moving averages can be shown", ErrInvalidMovingAverage, MaxMovingAverages)
+	}
+	for i, days := range movingAverages {
+		if days < 2 || days > MaxMovingAverageDays {
+			return fmt.Errorf("%w: days must be between 2 and %d", ErrInvalidMovingAverage, MaxMovingAverageDays)
+		}
+		if slices.Contains(movingAverages[:i], days) {
+			return fmt.Errorf("%w: %d days is repeated", ErrInvalidMovingAverage, days)
+		}
+	}
+	return nil
+}
+
+// Lookback is the number of days before the window needed to calculate movingAverages from its first date
+func Lookback(movingAverages []int) int {
+	lookback := 0
+	for _, days := range movingAverages {
+		lookback = max(lookback, days-1)
+	}
+	return lookback
+}
+
+type series struct {
+	label  string
+	colour string
+	// NaN where there's no value
+	values []float64
+}
+
+// PriceSVG renders an inline SVG line chart of the closes of the most recent window days of dailyData, which is
+// ordered newest first, along with any movingAverages and volume bars if volumes are known. Days beyond the window
+// are only used for moving averages, which start part way through the chart if there aren't enough of them
+func PriceSVG(dailyData []*stockclient.DayData, window int, movingAverages []int) template.HTML {
+	window = min(window, len(dailyData))
+	if window == 0 {
+		return ""
+	}
+
+	// Plot oldest first
+	dates := make([]time.Time, window)
+	closes := make([]float64, window)
+	volumes := make([]float64, window)
+	for i := range window {
+		dayData := dailyData[window-1-i]
+		dates[i] = dayData.Date
+		closes[i] = dayData.Close
+		volumes[i] = dayData.Volume
+	}
+
+	lines := []*series{{label: "Close", colour: closeColour, values: closes}}
+	for i, days := range movingAverages {
+		lines = append(lines, &series{
+			label:  fmt.Sprintf("%d day average", days),
+			colour: movingAverageColours[i%len(movingAverageColours)],
+			values: movingAverage(dailyData, window, days),
+		})
+	}
+
+	low, high := valueRange(lines)
+	maxVolume := slices.Max(volumes)
+
+	plotWidth := float64(width - marginLeft - marginRight)
+	plotHeight := float64(height - marginTop - marginBottom)
+	priceHeight := plotHeight
+	if maxVolume > 0 {
+		priceHeight = plotHeight * (1 - volumeShare)
+	}
+	// Each day is plotted in the middle of an equal slot so volume bars stay inside the plot
+	slotWidth := plotWidth / float64(window)
+	x := func(i int) float64 {
+		return marginLeft + (float64(i)+0.5)*slotWidth
+	}
+	y := func(value float64) float64 {
+		return marginTop + (high-value)/(high-low)*priceHeight
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="price-chart" viewBox="0 0 %d %d" width="100%%" role="img" font-family="arial, sans-serif" font-size="11">`, width, height)
+	fmt.Fprintf(&b, `<title>Closing prices from %s to %s</title>`, dates[0].Format(time.DateOnly), dates[window-1].Format(time.DateOnly))
+
+	// Price grid and axis labels
+	for i := range gridLines {
+		value := low + (high-low)*float64(i)/float64(gridLines-1)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#dddddd"/>`, marginLeft, y(value), width-marginRight, y(value))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`, marginLeft-6, y(value), formatPrice(value, high-low))
+	}
+
+	// Dates at each end and the middle
+	for _, i := range slices.Compact([]int{0, (window - 1) / 2, window - 1}) {
+		anchor := "middle"
+		switch {
+		case window == 1:
+		case i == 0:
+			anchor = "start"
+		case i == window-1:
+			anchor = "end"
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="%s">%s</text>`, x(i), height-6, anchor, dates[i].Format(time.DateOnly))
+	}
+
+	if maxVolume > 0 {
+		barWidth := slotWidth * 0.8
+		bottom := float64(marginTop) + plotHeight
+		for i, volume := range volumes {
+			barHeight := volume / maxVolume * plotHeight * volumeShare
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" fill-opacity="0.5"><title>%s volume %s</title></rect>`,
+				x(i)-barWidth/2, bottom-barHeight, barWidth, barHeight, volumeColour, dates[i].Format(time.DateOnly), strconv.FormatFloat(volume, 'f', -1, 64))
+		}
+	}
+
+	for _, line := range lines {
+		// Round caps make isolated points visible
+		fmt.Fprintf(&b, `<path d="%s" fill="none" stroke="%s" stroke-width="1.5" stroke-linecap="round" stroke-linejoin="round"/>`, path(line.values, x, y), line.colour)
+	}
+
+	// Legend
+	legendX := marginLeft
+	for _, line := range lines {
+		fmt.Fprintf(&b, `<rect x="%d" y="6" width="10" height="10" fill="%s"/>`, legendX, line.colour)
+		fmt.Fprintf(&b, `<text x="%d" y="15">%s</text>`, legendX+14, html.EscapeString(line.label))
+		legendX += 14 + 7*len(line.label) + 16
+	}
+
+	b.WriteString(`</svg>`)
+	// Everything written is either a number, a date or escaped
+	return template.HTML(b.String())
+}
+
+// movingAverage returns the average close over days for each of the most recent window days of dailyData, oldest
+// first. Values are NaN where there isn't enough data
+func movingAverage(dailyData []*stockclient.DayData, window int, days int) []float64 {
+	averages := make([]float64, window)
+	for i := range window {
+		// Index into dailyData, which is newest first, of the date being averaged
+		start := window - 1 - i
+		if start+days > len(dailyData) {
+			averages[i] = math.NaN()
+			continue
+		}
+		sum := float64(0)
+		for _, dayData := range dailyData[start : start+days] {
+			sum += dayData.Close
+		}
+		averages[i] = sum / float64(days)
+	}
+	return averages
+}
+
+// valueRange returns the range of values to plot, padded so lines don't touch the edges
+func valueRange(lines []*series) (float64, float64) {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, line := range lines {
+		for _, value := range line.values {
+			if !math.IsNaN(value) {
+				low = min(low, value)
+				high = max(high, value)
+			}
+		}
+	}
+	padding := (high - low) * 0.05
+	// Flat lines are plotted in the middle
+	if padding == 0 {
+		padding = math.Abs(high) * 0.05
+	}
+	if padding == 0 {
+		padding = 1
+	}
+	return low - padding, high + padding
+}
+
+// path returns SVG path data joining values, starting a new segment after any NaN values
+func path(values []float64, x func(int) float64, y func(float64) float64) string {
+	var b strings.Builder
+	move := true
+	for i, value := range values {
+		if math.IsNaN(value) {
+			move = true
+			continue
+		}
+		command := "L"
+		if move {
+			command = "M"
+			move = false
+		}
+		fmt.Fprintf(&b, "%s%.1f %.1f ", command, x(i), y(value))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// formatPrice formats an axis label with enough decimal places to distinguish values across span
+func formatPrice(value float64, span float64) string {
+	decimals := 2
+	if span < 0.1 {
+		decimals = 4
+	}
+	return strconv.FormatFloat(value, 'f', decimals, 64)
+}
@@ -0,0 +1,94 @@
+package chart
+
+import (
+	"math"
+	"stockticker/internal/stockclient"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// days returns daily data sorted newest first for the given closes, which are oldest first
+func days(volume float64, closes ...float64) []*stockclient.DayData {
+	dailyData := []*stockclient.DayData{}
+	date := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	for _, price := range closes {
+		dailyData = append([]*stockclient.DayData{{Date: date, Close: price, Volume: volume}}, dailyData...)
+		date = date.AddDate(0, 0, 1)
+	}
+	return dailyData
+}
+
+func TestValidateMovingAverages(t *testing.T) {
+	require.NoError(t, ValidateMovingAverages(nil))
+	require.NoError(t, ValidateMovingAverages([]int{2, 50, 200}))
+
+	for name, movingAverages := range map[string][]int{
+		"too few days":  {1},
+		"too many days": {201},
+		"repeated":      {20, 20},
+		"too many":      {5, 10, 20, 50},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.ErrorIs(t, ValidateMovingAverages(movingAverages), ErrInvalidMovingAverage)
+		})
+	}
+
+	require.Equal(t, 0, Lookback(nil))
+	require.Equal(t, 49, Lookback([]int{20, 50}))
+}
+
+func TestMovingAverage(t *testing.T) {
+	dailyData := days(0, 1, 2, 3, 4, 5)
+
+	t.Run("Days before the window are used", func(t *testing.T) {
+		require.Equal(t, []float64{2, 3, 4}, movingAverage(dailyData, 3, 3))
+	})
+
+	t.Run("NaN without enough data", func(t *testing.T) {
+		averages := movingAverage(dailyData, 5, 3)
+		require.True(t, math.IsNaN(averages[0]))
+		require.True(t, math.IsNaN(averages[1]))
+		require.Equal(t, []float64{2, 3, 4}, averages[2:])
+	})
+}
+
+func TestPath(t *testing.T) {
+	x := func(i int) float64 { return float64(i) }
+	y := func(v float64) float64 { return v }
+
+	require.Equal(t, "M0.0 1.0 L1.0 2.0", path([]float64{1, 2}, x, y))
+	require.Equal(t, "M1.0 2.0 L2.0 3.0 M4.0 5.0", path([]float64{math.NaN(), 2, 3, math.NaN(), 5}, x, y))
+	require.Equal(t, "", path([]float64{math.NaN()}, x, y))
+}
+
+func TestPriceSVG(t *testing.T) {
+	t.Run("Closes, moving average and volumes", func(t *testing.T) {
+		svg := string(PriceSVG(days(1000, 10, 11, 12, 11, 13), 3, []int{2}))
+		require.True(t, strings.HasPrefix(svg, "<svg "))
+		require.True(t, strings.HasSuffix(svg, "</svg>"))
+		require.Contains(t, svg, "<title>Closing prices from 2024-10-03 to 2024-10-05</title>")
+		require.Contains(t, svg, "2 day average")
+		require.Equal(t, 2, strings.Count(svg, "<path "))
+		require.Equal(t, 3, strings.Count(svg, "volume 1000"))
+	})
+
+	t.Run("No volume bars without volumes", func(t *testing.T) {
+		svg := string(PriceSVG(days(0, 1.08, 1.09), 10, nil))
+		require.Contains(t, svg, "<title>Closing prices from 2024-10-01 to 2024-10-02</title>")
+		require.NotContains(t, svg, "volume")
+	})
+
+	t.Run("A flat single day", func(t *testing.T) {
+		svg := string(PriceSVG(days(0, 5), 10, nil))
+		require.Contains(t, svg, `<path d="M424.0 200.0"`)
+		require.Contains(t, svg, `text-anchor="middle">2024-10-01</text>`)
+		require.NotContains(t, svg, "NaN")
+	})
+
+	t.Run("No data", func(t *testing.T) {
+		require.Empty(t, PriceSVG(nil, 10, nil))
+	})
+}
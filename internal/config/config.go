@@ -0,0 +1,546 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"stockticker/internal/datafile"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AppName = "stockticker"
+
+	maxStreamSymbols = 100
+)
+
+// Secret is a string that's redacted when printed
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "REDACTED"
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+type HostPort struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(hp.Port))
+}
+
+type CacheConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Redis   HostPort `yaml:"redis"`
+	// Bounds each cache read and write
+	Timeout time.Duration `yaml:"timeout"`
+	TTLs    CacheTTLs     `yaml:"ttls"`
+}
+
+// CacheTTLs are how long responses are cached for. Daily data is always cached until the next day
+type CacheTTLs struct {
+	Quote        time.Duration `yaml:"quote"`
+	SymbolSearch time.Duration `yaml:"symbolSearch"`
+	Overview     time.Duration `yaml:"overview"`
+}
+
+type UpstreamConfig struct {
+	// 0 is unlimited
+	RequestsPerMinute int `yaml:"requestsPerMinute"`
+	// Symbols fetched at once for a watchlist, portfolio or comparison
+	MaxConcurrentFetches int `yaml:"maxConcurrentFetches"`
+	// Requests each API key can make per UTC day, used to report the remaining quota. 0 is unknown
+	DailyQuotaPerKey int `yaml:"dailyQuotaPerKey"`
+}
+
+type StreamsConfig struct {
+	QuotePollInterval time.Duration `yaml:"quotePollInterval"`
+	// Concurrent quote streams, including WebSockets
+	Max int `yaml:"max"`
+	// Symbols per quote stream or WebSocket
+	MaxSymbols int `yaml:"maxSymbols"`
+}
+
+type AlertsConfig struct {
+	RulesFile     string   `yaml:"rulesFile"`
+	Webhooks      []string `yaml:"webhooks"`
+	WebhookSecret Secret   `yaml:"webhookSecret"`
+	// How often every rule is checked against its symbol's daily data
+	CheckInterval time.Duration `yaml:"checkInterval"`
+}
+
+// ListenerConfig is an optional HTTP listener
+type ListenerConfig struct {
+	Enabled  bool `yaml:"enabled"`
+	HostPort `yaml:",inline"`
+}
+
+type MonitoringConfig struct {
+	Pprof      ListenerConfig `yaml:"pprof"`
+	Prometheus ListenerConfig `yaml:"prometheus"`
+	// Also serve /metrics on the main listener, e.g. when Prometheus has its own listener disabled
+	MetricsOnMainListener bool          `yaml:"metricsOnMainListener"`
+	Tracing               TracingConfig `yaml:"tracing"`
+}
+
+// TracingConfig configures exporting OpenTelemetry traces
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// The base URL of an OTLP/HTTP collector. Spans are sent to its /v1/traces path
+	Endpoint string `yaml:"endpoint"`
+	// The fraction of new traces that are sampled. Requests that are part of a sampled trace are always sampled
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// Config is the complete configuration. Settings are taken from, in increasing order of precedence, defaults, the
+// config file, environment variables and command line flags
+type Config struct {
+	Symbol  string `yaml:"symbol"`
+	NumDays int    `yaml:"numDays"`
+	// One or more comma separated keys. Alternatively APIKeyFile holds one key per line
+	APIKey     Secret `yaml:"apiKey"`
+	APIKeyFile string `yaml:"apiKeyFile"`
+	// The bearer token required to change watchlists and alert rules via the API. Changes are disabled if it's not set
+	APIToken Secret `yaml:"apiToken"`
+	// One of logrus' levels, e.g. debug or info
+	LogLevel string `yaml:"logLevel"`
+	// text or json
+	LogFormat string `yaml:"logFormat"`
+
+	Listen HostPort `yaml:"listen"`
+	// How long shutdown may take, including waiting for in-flight requests to complete
+	MaxRequestDuration time.Duration `yaml:"maxRequestDuration"`
+
+	Cache      CacheConfig         `yaml:"cache"`
+	Upstream   UpstreamConfig      `yaml:"upstream"`
+	Streams    StreamsConfig       `yaml:"streams"`
+	Watchlists map[string][]string `yaml:"watchlists"`
+	Alerts     AlertsConfig        `yaml:"alerts"`
+	// A YAML, JSON or TOML file of portfolio positions
+	PortfolioFile string           `yaml:"portfolioFile"`
+	Monitoring    MonitoringConfig `yaml:"monitoring"`
+
+	// How often the config file is checked for changes. 0 disables checking, but SIGHUP still reloads it
+	ReloadInterval time.Duration `yaml:"reloadInterval"`
+
+	// The config file the settings were read from, if any
+	File string `yaml:"-"`
+	// Print the configuration and exit rather than starting
+	PrintConfig bool `yaml:"-"`
+}
+
+// Default returns the configuration used for anything that isn't set
+func Default() *Config {
+	return &Config{
+		LogLevel:           "info",
+		LogFormat:          "json",
+		Listen:             HostPort{Host: "0.0.0.0", Port: 8080},
+		MaxRequestDuration: 30 * time.Second,
+		Cache: CacheConfig{
+			Redis:   HostPort{Host: "127.0.0.1", Port: 6379},
+			Timeout: 15 * time.Second,
+			TTLs: CacheTTLs{
+				Quote:        time.Minute,
+				SymbolSearch: 24 * time.Hour,
+				Overview:     24 * time.Hour,
+			},
+		},
+		Upstream: UpstreamConfig{
+			MaxConcurrentFetches: 4,
+		},
+		Streams: StreamsConfig{
+			QuotePollInterval: 30 * time.Second,
+			Max:               100,
+			MaxSymbols:        20,
+		},
+		Watchlists: map[string][]string{},
+		Alerts: AlertsConfig{
+			Webhooks:      []string{},
+			CheckInterval: 15 * time.Minute,
+		},
+		Monitoring: MonitoringConfig{
+			Pprof:      ListenerConfig{Enabled: true, HostPort: HostPort{Host: "127.0.0.1", Port: 6060}},
+			Prometheus: ListenerConfig{Enabled: true, HostPort: HostPort{Host: "0.0.0.0", Port: 9102}},
+			Tracing:    TracingConfig{Endpoint: "http://localhost:4318", SampleRatio: 1},
+		},
+		ReloadInterval: 10 * time.Second,
+	}
+}
+
+// Load builds the configuration from command line args, excluding the program name, the config file given by
+// --config or CONFIG_FILE and environment variables read with lookupEnv. All invalid settings are reported together
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	flagCfg := Default()
+	flags, watchlists := newFlagSet(flagCfg)
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	cfg.File = flagCfg.File
+	if cfg.File == "" {
+		cfg.File, _ = lookupEnv("CONFIG_FILE")
+	}
+	cfg.PrintConfig = flagCfg.PrintConfig
+	if cfg.File != "" {
+		if err := cfg.readFile(cfg.File); err != nil {
+			return nil, err
+		}
+	}
+
+	errs := cfg.applyEnv(lookupEnv)
+	errs = append(errs, cfg.applyFlags(flags, flagCfg, *watchlists)...)
+	// Printing shows the config as it was given, so invalid settings can be found and fixed
+	if !cfg.PrintConfig {
+		errs = append(errs, cfg.Validate())
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func newFlagSet(cfg *Config) (*pflag.FlagSet, *[]string) {
+	flags := pflag.NewFlagSet(AppName, pflag.ContinueOnError)
+	flags.SortFlags = false
+
+	flags.StringVar(&cfg.File, "config", "", "A YAML, JSON or TOML config file. Can also be set with CONFIG_FILE")
+	flags.BoolVar(&cfg.PrintConfig, "print-config", false, "Print the effective configuration, with secrets redacted, and exit")
+	flags.DurationVar(&cfg.ReloadInterval, "config-reload-interval", cfg.ReloadInterval, "How often the config file is checked for changes. 0 disables checking, but SIGHUP still reloads it")
+	flags.StringVar(&cfg.APIKeyFile, "api-key-file", cfg.APIKeyFile, "A file of API keys, one per line, that's re-read when it changes. Can also be set with APIKEY_FILE")
+	flags.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "The minimum level of log messages, e.g. debug or info")
+	flags.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "The format of log messages, text or json")
+	flags.StringVar(&cfg.Listen.Host, "listen-ip", cfg.Listen.Host, "The IP address to listen on for HTTP requests")
+	flags.IntVar(&cfg.Listen.Port, "listen-port", cfg.Listen.Port, "The port to listen on for HTTP requests. 0 picks any free port")
+	flags.DurationVar(&cfg.MaxRequestDuration, "max-request-duration", cfg.MaxRequestDuration, "How long shutdown may take, including waiting for in-flight requests to complete")
+	flags.BoolVar(&cfg.Cache.Enabled, "enable-cache", cfg.Cache.Enabled, "Enable/disable caching")
+	flags.StringVar(&cfg.Cache.Redis.Host, "redis-host", cfg.Cache.Redis.Host, "The Redis host address to connect to")
+	flags.IntVar(&cfg.Cache.Redis.Port, "redis-port", cfg.Cache.Redis.Port, "The Redis port to connect to")
+	flags.DurationVar(&cfg.Cache.Timeout, "cache-timeout", cfg.Cache.Timeout, "The maximum duration of each cache read or write")
+	flags.DurationVar(&cfg.Streams.QuotePollInterval, "quote-poll-interval", cfg.Streams.QuotePollInterval, "How often quotes are refreshed for streaming clients")
+	flags.IntVar(&cfg.Streams.Max, "max-streams", cfg.Streams.Max, "The maximum number of concurrent quote streams, including WebSockets")
+	flags.IntVar(&cfg.Streams.MaxSymbols, "max-stream-symbols", cfg.Streams.MaxSymbols, "The maximum number of symbols per quote stream or WebSocket")
+	flags.IntVar(&cfg.Upstream.RequestsPerMinute, "upstream-requests-per-minute", cfg.Upstream.RequestsPerMinute, "The maximum number of requests per minute to the stock data provider. 0 is unlimited")
+	flags.IntVar(&cfg.Upstream.MaxConcurrentFetches, "max-concurrent-fetches", cfg.Upstream.MaxConcurrentFetches, "The maximum number of symbols fetched at once for a watchlist, portfolio or comparison")
+	flags.IntVar(&cfg.Upstream.DailyQuotaPerKey, "upstream-daily-quota-per-key", cfg.Upstream.DailyQuotaPerKey, "The number of requests each API key can make per day, used to report the remaining quota. 0 is unknown")
+	watchlists := flags.StringArray("watchlist", nil, "A read-only watchlist in the form name=SYMBOL,SYMBOL. Can be repeated")
+	flags.BoolVar(&cfg.Monitoring.Pprof.Enabled, "enable-pprof", cfg.Monitoring.Pprof.Enabled, "Enable/disable the pprof server")
+	flags.StringVar(&cfg.Monitoring.Pprof.Host, "pprof-ip", cfg.Monitoring.Pprof.Host, "The IP address the pprof server listens on")
+	flags.IntVar(&cfg.Monitoring.Pprof.Port, "pprof-port", cfg.Monitoring.Pprof.Port, "The port the pprof server listens on")
+	flags.BoolVar(&cfg.Monitoring.Prometheus.Enabled, "enable-prometheus", cfg.Monitoring.Prometheus.Enabled, "Enable/disable the Prometheus metrics server")
+	flags.StringVar(&cfg.Monitoring.Prometheus.Host, "prometheus-ip", cfg.Monitoring.Prometheus.Host, "The IP address the Prometheus metrics server listens on")
+	flags.IntVar(&cfg.Monitoring.Prometheus.Port, "prometheus-port", cfg.Monitoring.Prometheus.Port, "The port the Prometheus metrics server listens on")
+	flags.BoolVar(&cfg.Monitoring.MetricsOnMainListener, "metrics-on-main-listener", cfg.Monitoring.MetricsOnMainListener, "Also serve Prometheus metrics on /metrics of the main HTTP listener")
+	flags.BoolVar(&cfg.Monitoring.Tracing.Enabled, "enable-tracing", cfg.Monitoring.Tracing.Enabled, "Enable/disable exporting OpenTelemetry traces")
+	flags.StringVar(&cfg.Monitoring.Tracing.Endpoint, "tracing-endpoint", cfg.Monitoring.Tracing.Endpoint, "The base URL of the OTLP/HTTP collector traces are exported to")
+	flags.Float64Var(&cfg.Monitoring.Tracing.SampleRatio, "tracing-sample-ratio", cfg.Monitoring.Tracing.SampleRatio, "The fraction of new traces that are sampled, between 0 and 1")
+	flags.StringVar(&cfg.Alerts.RulesFile, "alert-rules-file", cfg.Alerts.RulesFile, "A YAML, JSON or TOML file of read-only alert rules")
+	flags.StringVar(&cfg.PortfolioFile, "portfolio-file", cfg.PortfolioFile, "A YAML, JSON or TOML file of portfolio positions")
+	flags.StringArrayVar(&cfg.Alerts.Webhooks, "alert-webhook", cfg.Alerts.Webhooks, "A URL to deliver alerts to. Can be repeated. Requires ALERT_WEBHOOK_SECRET")
+	flags.DurationVar(&cfg.Alerts.CheckInterval, "alert-check-interval", cfg.Alerts.CheckInterval, "How often alert rules are checked against the latest daily data")
+	return flags, watchlists
+}
+
+// readFile overlays the settings in a YAML, JSON or TOML file
+func (cfg *Config) readFile(path string) error {
+	// An empty file leaves the defaults in place
+	if err := datafile.Load(path, cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	return nil
+}
+
+// applyEnv overlays the settings given by environment variables
+func (cfg *Config) applyEnv(lookupEnv func(string) (string, bool)) []error {
+	errs := []error{}
+	if val, ok := lookupEnv("SYMBOL"); ok {
+		cfg.Symbol = val
+	}
+	if val, ok := lookupEnv("NDAYS"); ok {
+		numDays, err := strconv.Atoi(val)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("NDAYS not a valid integer"))
+		} else {
+			cfg.NumDays = numDays
+		}
+	}
+	// The key or key file set with the highest precedence replaces the other
+	apiKey, hasAPIKey := lookupEnv("APIKEY")
+	apiKeyFile, hasAPIKeyFile := lookupEnv("APIKEY_FILE")
+	switch {
+	case hasAPIKey && hasAPIKeyFile:
+		errs = append(errs, fmt.Errorf("only one of APIKEY and APIKEY_FILE can be set"))
+	case hasAPIKey:
+		cfg.APIKey = Secret(apiKey)
+		cfg.APIKeyFile = ""
+	case hasAPIKeyFile:
+		cfg.APIKey = ""
+		cfg.APIKeyFile = apiKeyFile
+	}
+	if val, ok := lookupEnv("API_TOKEN"); ok {
+		cfg.APIToken = Secret(val)
+	}
+	if val, ok := lookupEnv("ALERT_WEBHOOK_SECRET"); ok {
+		cfg.Alerts.WebhookSecret = Secret(val)
+	}
+	if val, ok := lookupEnv("LOG_LEVEL"); ok {
+		cfg.LogLevel = val
+	}
+	if val, ok := lookupEnv("LOG_FORMAT"); ok {
+		cfg.LogFormat = val
+	}
+	return errs
+}
+
+// applyFlags overlays the flags set on the command line, which were parsed into flagCfg
+func (cfg *Config) applyFlags(flags *pflag.FlagSet, flagCfg *Config, watchlists []string) []error {
+	errs := []error{}
+	flags.Visit(func(f *pflag.Flag) {
+		switch f.Name {
+		case "api-key-file":
+			cfg.APIKey = ""
+			cfg.APIKeyFile = flagCfg.APIKeyFile
+		case "config-reload-interval":
+			cfg.ReloadInterval = flagCfg.ReloadInterval
+		case "log-level":
+			cfg.LogLevel = flagCfg.LogLevel
+		case "log-format":
+			cfg.LogFormat = flagCfg.LogFormat
+		case "listen-ip":
+			cfg.Listen.Host = flagCfg.Listen.Host
+		case "listen-port":
+			cfg.Listen.Port = flagCfg.Listen.Port
+		case "max-request-duration":
+			cfg.MaxRequestDuration = flagCfg.MaxRequestDuration
+		case "enable-cache":
+			cfg.Cache.Enabled = flagCfg.Cache.Enabled
+		case "redis-host":
+			cfg.Cache.Redis.Host = flagCfg.Cache.Redis.Host
+		case "redis-port":
+			cfg.Cache.Redis.Port = flagCfg.Cache.Redis.Port
+		case "cache-timeout":
+			cfg.Cache.Timeout = flagCfg.Cache.Timeout
+		case "quote-poll-interval":
+			cfg.Streams.QuotePollInterval = flagCfg.Streams.QuotePollInterval
+		case "max-streams":
+			cfg.Streams.Max = flagCfg.Streams.Max
+		case "max-stream-symbols":
+			cfg.Streams.MaxSymbols = flagCfg.Streams.MaxSymbols
+		case "upstream-requests-per-minute":
+			cfg.Upstream.RequestsPerMinute = flagCfg.Upstream.RequestsPerMinute
+		case "max-concurrent-fetches":
+			cfg.Upstream.MaxConcurrentFetches = flagCfg.Upstream.MaxConcurrentFetches
+		case "upstream-daily-quota-per-key":
+			cfg.Upstream.DailyQuotaPerKey = flagCfg.Upstream.DailyQuotaPerKey
+		case "enable-pprof":
+			cfg.Monitoring.Pprof.Enabled = flagCfg.Monitoring.Pprof.Enabled
+		case "pprof-ip":
+			cfg.Monitoring.Pprof.Host = flagCfg.Monitoring.Pprof.Host
+		case "pprof-port":
+			cfg.Monitoring.Pprof.Port = flagCfg.Monitoring.Pprof.Port
+		case "enable-prometheus":
+			cfg.Monitoring.Prometheus.Enabled = flagCfg.Monitoring.Prometheus.Enabled
+		case "prometheus-ip":
+			cfg.Monitoring.Prometheus.Host = flagCfg.Monitoring.Prometheus.Host
+		case "prometheus-port":
+			cfg.Monitoring.Prometheus.Port = flagCfg.Monitoring.Prometheus.Port
+		case "metrics-on-main-listener":
+			cfg.Monitoring.MetricsOnMainListener = flagCfg.Monitoring.MetricsOnMainListener
+		case "enable-tracing":
+			cfg.Monitoring.Tracing.Enabled = flagCfg.Monitoring.Tracing.Enabled
+		case "tracing-endpoint":
+			cfg.Monitoring.Tracing.Endpoint = flagCfg.Monitoring.Tracing.Endpoint
+		case "tracing-sample-ratio":
+			cfg.Monitoring.Tracing.SampleRatio = flagCfg.Monitoring.Tracing.SampleRatio
+		case "alert-rules-file":
+			cfg.Alerts.RulesFile = flagCfg.Alerts.RulesFile
+		case "portfolio-file":
+			cfg.PortfolioFile = flagCfg.PortfolioFile
+		case "alert-webhook":
+			cfg.Alerts.Webhooks = flagCfg.Alerts.Webhooks
+		case "alert-check-interval":
+			cfg.Alerts.CheckInterval = flagCfg.Alerts.CheckInterval
+		case "watchlist":
+			// Flags replace watchlists of the same name from the config file
+			for _, watchlist := range watchlists {
+				name, symbols, found := strings.Cut(watchlist, "=")
+				if !found || symbols == "" {
+					errs = append(errs, fmt.Errorf("--watchlist '%s' must be in the form name=SYMBOL,SYMBOL", watchlist))
+					continue
+				}
+				cfg.Watchlists[name] = strings.Split(symbols, ",")
+			}
+		}
+	})
+	return errs
+}
+
+// Validate checks every setting, returning all problems found
+func (cfg *Config) Validate() error {
+	errs := []error{}
+	if cfg.Symbol == "" {
+		errs = append(errs, fmt.Errorf("symbol not set. Set SYMBOL or symbol in the config file"))
+	}
+	if cfg.NumDays <= 0 {
+		errs = append(errs, fmt.Errorf("numDays must be greater than zero. Set NDAYS or numDays in the config file"))
+	}
+	switch {
+	case cfg.APIKey == "" && cfg.APIKeyFile == "":
+		errs = append(errs, fmt.Errorf("apiKey not set. Set APIKEY, APIKEY_FILE, --api-key-file, or apiKey or apiKeyFile in the config file"))
+	case cfg.APIKey != "" && cfg.APIKeyFile != "":
+		errs = append(errs, fmt.Errorf("only one of apiKey and apiKeyFile can be set in the config file"))
+	}
+	if _, err := log.ParseLevel(cfg.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("logLevel: %w", err))
+	}
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		errs = append(errs, fmt.Errorf("logFormat must be text or json"))
+	}
+
+	type namedHostPort struct {
+		name string
+		HostPort
+	}
+	// Port 0 listens on any free port, which is logged
+	if cfg.Listen.Port < 0 || cfg.Listen.Port > 65535 {
+		errs = append(errs, fmt.Errorf("listen.port must be between 0 and 65535"))
+	}
+	hostPorts := []namedHostPort{{"cache.redis", cfg.Cache.Redis}}
+	if cfg.Monitoring.Pprof.Enabled {
+		hostPorts = append(hostPorts, namedHostPort{"monitoring.pprof", cfg.Monitoring.Pprof.HostPort})
+	}
+	if cfg.Monitoring.Prometheus.Enabled {
+		hostPorts = append(hostPorts, namedHostPort{"monitoring.prometheus", cfg.Monitoring.Prometheus.HostPort})
+	}
+	for _, hp := range hostPorts {
+		if hp.Port <= 0 || hp.Port > 65535 {
+			errs = append(errs, fmt.Errorf("%s.port must be between 1 and 65535", hp.name))
+		}
+	}
+
+	if cfg.Monitoring.Tracing.Enabled {
+		if u, err := url.Parse(cfg.Monitoring.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("monitoring.tracing.endpoint must be an http or https URL"))
+		}
+		if cfg.Monitoring.Tracing.SampleRatio < 0 || cfg.Monitoring.Tracing.SampleRatio > 1 {
+			errs = append(errs, fmt.Errorf("monitoring.tracing.sampleRatio must be between 0 and 1"))
+		}
+	}
+
+	for _, d := range []struct {
+		name string
+		time.Duration
+	}{
+		{"maxRequestDuration", cfg.MaxRequestDuration},
+		{"cache.timeout", cfg.Cache.Timeout},
+		{"cache.ttls.quote", cfg.Cache.TTLs.Quote},
+		{"cache.ttls.symbolSearch", cfg.Cache.TTLs.SymbolSearch},
+		{"cache.ttls.overview", cfg.Cache.TTLs.Overview},
+		{"streams.quotePollInterval", cfg.Streams.QuotePollInterval},
+		{"alerts.checkInterval", cfg.Alerts.CheckInterval},
+	} {
+		if d.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be greater than zero", d.name))
+		}
+	}
+
+	if cfg.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("reloadInterval must not be negative"))
+	}
+	if cfg.Streams.Max <= 0 {
+		errs = append(errs, fmt.Errorf("streams.max must be greater than zero"))
+	}
+	if cfg.Streams.MaxSymbols <= 0 || cfg.Streams.MaxSymbols > maxStreamSymbols {
+		errs = append(errs, fmt.Errorf("streams.maxSymbols must be between 1 and %d", maxStreamSymbols))
+	}
+	if cfg.Upstream.RequestsPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("upstream.requestsPerMinute must not be negative"))
+	}
+	if cfg.Upstream.MaxConcurrentFetches <= 0 {
+		errs = append(errs, fmt.Errorf("upstream.maxConcurrentFetches must be greater than zero"))
+	}
+	if cfg.Upstream.DailyQuotaPerKey < 0 {
+		errs = append(errs, fmt.Errorf("upstream.dailyQuotaPerKey must not be negative"))
+	}
+	if len(cfg.Alerts.Webhooks) > 0 && cfg.Alerts.WebhookSecret == "" {
+		errs = append(errs, fmt.Errorf("alerts.webhookSecret is required to deliver alerts. Set ALERT_WEBHOOK_SECRET or alerts.webhookSecret in the config file"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// APIKeys returns the API keys from APIKey or APIKeyFile. Blank lines and lines starting with # in the file are
+// ignored
+func (cfg *Config) APIKeys() ([]string, error) {
+	var keys []string
+	if cfg.APIKeyFile == "" {
+		for _, key := range strings.Split(string(cfg.APIKey), ",") {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				return nil, fmt.Errorf("apiKey contains a blank key")
+			}
+			keys = append(keys, key)
+		}
+		return keys, nil
+	}
+
+	data, err := os.ReadFile(cfg.APIKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API key file: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no API keys in %s", cfg.APIKeyFile)
+	}
+	return keys, nil
+}
+
+// Level returns the parsed log level
+func (cfg *Config) Level() log.Level {
+	level, err := log.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return log.InfoLevel
+	}
+	return level
+}
+
+// Formatter returns the formatter for the log format
+func (cfg *Config) Formatter() log.Formatter {
+	if cfg.LogFormat == "json" {
+		return &log.JSONFormatter{}
+	}
+	return &log.TextFormatter{}
+}
+
+// Print writes the configuration as YAML with secrets redacted
+func (cfg *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
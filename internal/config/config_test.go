@@ -0,0 +1,216 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// env returns a lookup function for the given environment variables
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		val, ok := vars[key]
+		return val, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+var requiredEnv = map[string]string{"SYMBOL": "MSFT", "NDAYS": "5", "APIKEY": "key"}
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := Load(nil, env(requiredEnv))
+		require.NoError(t, err)
+
+		expected := Default()
+		expected.Symbol = "MSFT"
+		expected.NumDays = 5
+		expected.APIKey = "key"
+		require.Equal(t, expected, cfg)
+	})
+
+	t.Run("Flags override environment variables, which override the file", func(t *testing.T) {
+		path := writeFile(t, `
+symbol: AAPL
+numDays: 10
+apiKey: file-key
+logLevel: debug
+listen:
+  port: 9000
+streams:
+  max: 10
+  quotePollInterval: 1m
+watchlists:
+  tech: [MSFT, AAPL]
+  banks: [JPM]
+`)
+		cfg, err := Load([]string{"--max-streams", "20", "--watchlist", "banks=C,WFC"}, env(map[string]string{
+			"CONFIG_FILE": path,
+			"NDAYS":       "7",
+			"LOG_LEVEL":   "warn",
+		}))
+		require.NoError(t, err)
+		require.Equal(t, path, cfg.File)
+		require.Equal(t, "AAPL", cfg.Symbol)
+		require.Equal(t, 7, cfg.NumDays)
+		require.Equal(t, Secret("file-key"), cfg.APIKey)
+		require.Equal(t, "warn", cfg.LogLevel)
+		require.Equal(t, HostPort{Host: "0.0.0.0", Port: 9000}, cfg.Listen)
+		require.Equal(t, 20, cfg.Streams.Max)
+		require.Equal(t, time.Minute, cfg.Streams.QuotePollInterval)
+		require.Equal(t, map[string][]string{"tech": {"MSFT", "AAPL"}, "banks": {"C", "WFC"}}, cfg.Watchlists)
+	})
+
+	t.Run("The config flag overrides CONFIG_FILE", func(t *testing.T) {
+		path := writeFile(t, "symbol: AAPL\n")
+		cfg, err := Load([]string{"--config", path}, env(map[string]string{"CONFIG_FILE": "missing.yaml", "NDAYS": "5", "APIKEY": "key"}))
+		require.NoError(t, err)
+		require.Equal(t, "AAPL", cfg.Symbol)
+	})
+
+	t.Run("API keys", func(t *testing.T) {
+		cfg, err := Load(nil, env(map[string]string{"SYMBOL": "MSFT", "NDAYS": "5", "APIKEY": "key-1, key-2"}))
+		require.NoError(t, err)
+		keys, err := cfg.APIKeys()
+		require.NoError(t, err)
+		require.Equal(t, []string{"key-1", "key-2"}, keys)
+
+		keyPath := writeFile(t, "# Rotated monthly\nkey-3\n\nkey-4\n")
+		path := writeFile(t, "apiKey: file-key\n")
+		cfg, err = Load([]string{"--config", path, "--api-key-file", keyPath}, env(map[string]string{"SYMBOL": "MSFT", "NDAYS": "5"}))
+		require.NoError(t, err)
+		require.Empty(t, cfg.APIKey)
+		keys, err = cfg.APIKeys()
+		require.NoError(t, err)
+		require.Equal(t, []string{"key-3", "key-4"}, keys)
+
+		path = writeFile(t, "apiKey: file-key\napiKeyFile: "+keyPath+"\n")
+		_, err = Load([]string{"--config", path}, env(map[string]string{"SYMBOL": "MSFT", "NDAYS": "5"}))
+		require.ErrorContains(t, err, "only one of apiKey and apiKeyFile can be set")
+	})
+
+	t.Run("TOML file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.toml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+symbol = "AAPL"
+numDays = 10
+apiKey = "file-key"
+
+[listen]
+port = 9000
+
+[streams]
+quotePollInterval = "1m"
+
+[watchlists]
+tech = ["MSFT", "AAPL"]
+`), 0o600))
+		cfg, err := Load([]string{"--config", path}, env(nil))
+		require.NoError(t, err)
+		require.Equal(t, "AAPL", cfg.Symbol)
+		require.Equal(t, 10, cfg.NumDays)
+		require.Equal(t, HostPort{Host: "0.0.0.0", Port: 9000}, cfg.Listen)
+		require.Equal(t, time.Minute, cfg.Streams.QuotePollInterval)
+		require.Equal(t, map[string][]string{"tech": {"MSFT", "AAPL"}}, cfg.Watchlists)
+
+		require.NoError(t, os.WriteFile(path, []byte("symbol = \"AAPL\"\nsymbols = [\"MSFT\"]\n"), 0o600))
+		_, err = Load([]string{"--config", path}, env(nil))
+		require.ErrorContains(t, err, "field symbols not found")
+	})
+
+	t.Run("Monitoring listeners", func(t *testing.T) {
+		path := writeFile(t, "monitoring:\n  pprof:\n    enabled: false\n    port: 0\n  prometheus:\n    port: 9200\n")
+		cfg, err := Load([]string{"--config", path, "--prometheus-ip", "127.0.0.1", "--enable-prometheus=false", "--metrics-on-main-listener"}, env(requiredEnv))
+		require.NoError(t, err)
+		require.Equal(t, MonitoringConfig{
+			Pprof:                 ListenerConfig{HostPort: HostPort{Host: "127.0.0.1"}},
+			Prometheus:            ListenerConfig{HostPort: HostPort{Host: "127.0.0.1", Port: 9200}},
+			MetricsOnMainListener: true,
+			Tracing:               TracingConfig{Endpoint: "http://localhost:4318", SampleRatio: 1},
+		}, cfg.Monitoring)
+
+		// Only enabled listeners are validated
+		_, err = Load([]string{"--config", path, "--enable-pprof"}, env(requiredEnv))
+		require.EqualError(t, err, "monitoring.pprof.port must be between 1 and 65535")
+	})
+
+	t.Run("Tracing", func(t *testing.T) {
+		path := writeFile(t, "monitoring:\n  tracing:\n    endpoint: collector:4318\n    sampleRatio: 2\n")
+		// Tracing settings are only validated when it's enabled
+		_, err := Load([]string{"--config", path}, env(requiredEnv))
+		require.NoError(t, err)
+		_, err = Load([]string{"--config", path, "--enable-tracing"}, env(requiredEnv))
+		require.EqualError(t, err, `monitoring.tracing.endpoint must be an http or https URL
+monitoring.tracing.sampleRatio must be between 0 and 1`)
+
+		cfg, err := Load([]string{"--config", path, "--enable-tracing", "--tracing-endpoint", "https://collector:4318", "--tracing-sample-ratio", "0.1"}, env(requiredEnv))
+		require.NoError(t, err)
+		require.Equal(t, TracingConfig{Enabled: true, Endpoint: "https://collector:4318", SampleRatio: 0.1}, cfg.Monitoring.Tracing)
+	})
+
+	t.Run("Unknown settings in the file", func(t *testing.T) {
+		path := writeFile(t, "symbol: AAPL\nsymbols: [MSFT]\n")
+		_, err := Load([]string{"--config", path}, env(requiredEnv))
+		require.ErrorContains(t, err, "field symbols not found")
+	})
+
+	t.Run("All invalid settings are reported", func(t *testing.T) {
+		_, err := Load([]string{"--max-streams", "0", "--watchlist", "tech", "--alert-webhook", "https://example.com", "--upstream-daily-quota-per-key", "-1"},
+			env(map[string]string{"NDAYS": "x", "LOG_LEVEL": "loud", "LOG_FORMAT": "xml"}))
+		require.EqualError(t, err, `NDAYS not a valid integer
+--watchlist 'tech' must be in the form name=SYMBOL,SYMBOL
+symbol not set. Set SYMBOL or symbol in the config file
+numDays must be greater than zero. Set NDAYS or numDays in the config file
+apiKey not set. Set APIKEY, APIKEY_FILE, --api-key-file, or apiKey or apiKeyFile in the config file
+logLevel: not a valid logrus Level: "loud"
+logFormat must be text or json
+streams.max must be greater than zero
+upstream.dailyQuotaPerKey must not be negative
+alerts.webhookSecret is required to deliver alerts. Set ALERT_WEBHOOK_SECRET or alerts.webhookSecret in the config file`)
+	})
+}
+
+func TestPrint(t *testing.T) {
+	cfg, err := Load([]string{"--print-config"}, env(map[string]string{
+		"SYMBOL":               "MSFT",
+		"NDAYS":                "5",
+		"APIKEY":               "secret-key",
+		"API_TOKEN":            "secret-token",
+		"ALERT_WEBHOOK_SECRET": "secret-signing-key",
+	}))
+	require.NoError(t, err)
+	require.True(t, cfg.PrintConfig)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+	require.Contains(t, buf.String(), "apiKey: REDACTED\n")
+	require.Contains(t, buf.String(), "apiToken: REDACTED\n")
+	require.Contains(t, buf.String(), "  webhookSecret: REDACTED\n")
+	require.Contains(t, buf.String(), "  quotePollInterval: 30s\n")
+	require.NotContains(t, buf.String(), "secret-")
+
+	// Invalid config can be printed so it can be fixed
+	invalid, err := Load([]string{"--print-config", "--max-streams", "0"}, env(nil))
+	require.NoError(t, err)
+	require.Equal(t, 0, invalid.Streams.Max)
+
+	// The printed config can be loaded again, apart from the redacted secrets
+	path := writeFile(t, buf.String())
+	reloaded, err := Load([]string{"--config", path}, env(nil))
+	require.NoError(t, err)
+	cfg.File = path
+	cfg.PrintConfig = false
+	cfg.APIKey = "REDACTED"
+	cfg.APIToken = "REDACTED"
+	cfg.Alerts.WebhookSecret = "REDACTED"
+	require.Equal(t, cfg, reloaded)
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Watcher reloads the configuration when its file or API key file changes or the process receives SIGHUP
+type Watcher struct {
+	args      []string
+	lookupEnv func(string) (string, bool)
+	// Applies a reloaded configuration. If it returns an error the current configuration is kept
+	apply func(old, new *Config) error
+	// Registered when the watcher is created so SIGHUP doesn't terminate the process before Run starts
+	hup chan os.Signal
+
+	mu      sync.Mutex
+	current *Config
+	// The content hashes of the watched files when they were last checked
+	fileHashes map[string][sha256.Size]byte
+}
+
+// NewWatcher creates a watcher for cfg, which was loaded from args and lookupEnv. apply is called with each valid
+// configuration that's loaded, so files it refers to, such as the alert rules, are read again too
+func NewWatcher(cfg *Config, args []string, lookupEnv func(string) (string, bool), apply func(old, new *Config) error) *Watcher {
+	w := &Watcher{
+		args:      args,
+		lookupEnv: lookupEnv,
+		apply:     apply,
+		hup:       make(chan os.Signal, 1),
+		current:   cfg,
+	}
+	signal.Notify(w.hup, syscall.SIGHUP)
+	w.fileChanged()
+	return w
+}
+
+// Run reloads the configuration until ctx is done. A SIGHUP received since the watcher was created is handled straight
+// away
+func (w *Watcher) Run(ctx context.Context) {
+	defer signal.Stop(w.hup)
+
+	var poll <-chan time.Time
+	if interval := w.Current().ReloadInterval; interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.hup:
+			log.Info("Received SIGHUP, reloading configuration")
+			_ = w.Reload()
+		case <-poll:
+			if file, changed := w.fileChanged(); changed {
+				log.Infof("%s changed, reloading configuration", file)
+				_ = w.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads the configuration again and applies it. Invalid configurations are logged and ignored
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	cfg, err := Load(w.args, w.lookupEnv)
+	if err != nil {
+		log.Errorf("Ignoring invalid configuration: %v", err)
+		return err
+	}
+	if err := w.apply(w.current, cfg); err != nil {
+		log.Errorf("Failed to apply configuration, keeping the current one: %v", err)
+		return err
+	}
+
+	if !reflect.DeepEqual(cfg.static(), w.current.static()) {
+		log.Warn("Some changed settings only take effect after a restart")
+	}
+	w.current = cfg
+	log.Info("Configuration reloaded")
+	return nil
+}
+
+// Current returns the configuration that was last applied
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// fileChanged reports whether the content of the config file or API key file has changed since they were last checked,
+// and which one. A file that can't be read counts as a change once, so the error is reported without repeating it every
+// poll
+func (w *Watcher) fileChanged() (string, bool) {
+	current := w.Current()
+	hashes := make(map[string][sha256.Size]byte)
+	changed := ""
+	for _, file := range []string{current.File, current.APIKeyFile} {
+		if file == "" {
+			continue
+		}
+		// Unreadable files have the zero hash
+		var hash [sha256.Size]byte
+		if content, err := os.ReadFile(file); err == nil {
+			hash = sha256.Sum256(content)
+		}
+		if prev, ok := w.fileHashes[file]; changed == "" && (!ok || prev != hash) {
+			changed = file
+		}
+		hashes[file] = hash
+	}
+	w.fileHashes = hashes
+	return changed, changed != ""
+}
+
+// static returns a copy of cfg without the settings that can be changed while running
+func (cfg *Config) static() Config {
+	static := *cfg
+	static.Symbol = ""
+	static.NumDays = 0
+	static.LogLevel = ""
+	static.LogFormat = ""
+	static.Cache.TTLs = CacheTTLs{}
+	static.Watchlists = nil
+	static.Alerts.RulesFile = ""
+	static.APIKey = ""
+	static.APIKeyFile = ""
+	return static
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireChanged(t *testing.T, w *Watcher, path string) {
+	file, changed := w.fileChanged()
+	require.True(t, changed)
+	require.Equal(t, path, file)
+}
+
+func requireUnchanged(t *testing.T, w *Watcher) {
+	_, changed := w.fileChanged()
+	require.False(t, changed)
+}
+
+func TestWatcher(t *testing.T) {
+	path := writeFile(t, "symbol: AAPL\nnumDays: 5\napiKey: key\n")
+	args := []string{"--config", path}
+	cfg, err := Load(args, env(nil))
+	require.NoError(t, err)
+
+	applied := []*Config{cfg}
+	var applyErr error
+	w := NewWatcher(cfg, args, env(nil), func(old, new *Config) error {
+		if applyErr != nil {
+			return applyErr
+		}
+		require.Same(t, applied[len(applied)-1], old)
+		applied = append(applied, new)
+		return nil
+	})
+	requireUnchanged(t, w)
+
+	require.NoError(t, os.WriteFile(path, []byte("symbol: MSFT\nnumDays: 10\napiKey: key\n"), 0o600))
+	requireChanged(t, w, path)
+	requireUnchanged(t, w)
+	require.NoError(t, w.Reload())
+	require.Len(t, applied, 2)
+	require.Equal(t, "MSFT", applied[1].Symbol)
+	require.Equal(t, 10, applied[1].NumDays)
+	require.Same(t, applied[1], w.Current())
+
+	t.Run("Invalid configurations are ignored", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("symbol: MSFT\nnumDays: 0\napiKey: key\n"), 0o600))
+		require.ErrorContains(t, w.Reload(), "numDays must be greater than zero")
+		require.Len(t, applied, 2)
+		require.Equal(t, 10, w.Current().NumDays)
+	})
+
+	t.Run("Configurations that fail to apply are ignored", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("symbol: IBM\nnumDays: 10\napiKey: key\n"), 0o600))
+		applyErr = errors.New("failed")
+		require.ErrorIs(t, w.Reload(), applyErr)
+		require.Equal(t, "MSFT", w.Current().Symbol)
+	})
+
+	t.Run("The API key file is watched", func(t *testing.T) {
+		keyPath := filepath.Join(t.TempDir(), "apikeys")
+		require.NoError(t, os.WriteFile(keyPath, []byte("key-1\n"), 0o600))
+		require.NoError(t, os.WriteFile(path, []byte("symbol: MSFT\nnumDays: 10\napiKeyFile: "+keyPath+"\n"), 0o600))
+		applyErr = nil
+		require.NoError(t, w.Reload())
+		requireChanged(t, w, path)
+		requireUnchanged(t, w)
+
+		require.NoError(t, os.WriteFile(keyPath, []byte("key-1\nkey-2\n"), 0o600))
+		requireChanged(t, w, keyPath)
+		requireUnchanged(t, w)
+	})
+
+	t.Run("A missing file is reported once", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+		requireChanged(t, w, path)
+		requireUnchanged(t, w)
+	})
+}
+
+func TestWatcherSIGHUP(t *testing.T) {
+	path := writeFile(t, "symbol: AAPL\nnumDays: 5\napiKey: key\n")
+	args := []string{"--config", path, "--config-reload-interval", "0"}
+	cfg, err := Load(args, env(nil))
+	require.NoError(t, err)
+
+	applied := make(chan *Config, 1)
+	w := NewWatcher(cfg, args, env(nil), func(old, new *Config) error {
+		applied <- new
+		return nil
+	})
+
+	// The signal arrives before Run starts, which would terminate the process if it wasn't handled yet
+	require.NoError(t, os.WriteFile(path, []byte("symbol: MSFT\nnumDays: 5\napiKey: key\n"), 0o600))
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	select {
+	case cfg := <-applied:
+		require.Equal(t, "MSFT", cfg.Symbol)
+	case <-time.After(5 * time.Second):
+		t.Fatal("The configuration wasn't reloaded")
+	}
+}
@@ -0,0 +1,254 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"stockticker/internal/alerts"
+	"stockticker/internal/cache"
+	"stockticker/internal/logging"
+	"stockticker/internal/stockclient"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	alertRulesCacheKey = "alert_rules"
+	// Long enough to cover weekends and holidays where the latest data point doesn't change
+	alertFiredTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	ErrAlertRuleReadOnly = errors.New("alert rule is defined in config and can't be changed")
+)
+
+type AlertNotifier interface {
+	// Notify is passed the context the alert fired in
+	Notify(ctx context.Context, alert *alerts.Alert)
+}
+
+type AlertRule struct {
+	alerts.Rule
+	// Rules defined in config can't be changed via the API
+	ReadOnly bool `json:"readOnly"`
+}
+
+// AlertController periodically evaluates every alert rule against its symbol's daily data and notifies of any that
+// fire. Each rule fires at most once per data point
+type AlertController struct {
+	stockCtrler *StockController
+	cache       cache.Client
+	notifier    AlertNotifier
+	interval    time.Duration
+	// Replaced as a whole on reload so it's never modified in place
+	configured atomic.Pointer[map[string]alerts.Rule]
+	editable   *editableStore[alerts.Rule]
+
+	// Holds the date each rule last fired for when the cache is disabled or unavailable
+	mu    sync.Mutex
+	fired map[string]time.Time
+}
+
+func NewAlertController(stockCtrler *StockController, cache cache.Client, notifier AlertNotifier, configured []alerts.Rule, interval time.Duration) (*AlertController, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("evaluation interval must be greater than zero")
+	}
+
+	ac := &AlertController{
+		stockCtrler: stockCtrler,
+		cache:       cache,
+		notifier:    notifier,
+		interval:    interval,
+		editable:    newEditableStore[alerts.Rule](cache, alertRulesCacheKey, stockCtrler.cacheTimeout),
+		fired:       make(map[string]time.Time),
+	}
+	if err := ac.SetConfigured(configured); err != nil {
+		return nil, err
+	}
+	return ac, nil
+}
+
+// SetConfigured replaces the read-only rules defined in config. Nothing is changed if any are invalid
+func (ac *AlertController) SetConfigured(configured []alerts.Rule) error {
+	rules := make(map[string]alerts.Rule)
+	for _, rule := range configured {
+		rule, err := newAlertRule(rule)
+		if err != nil {
+			return err
+		}
+		if _, ok := rules[rule.ID]; ok {
+			return fmt.Errorf("%w: duplicate id '%s'", alerts.ErrInvalidRule, rule.ID)
+		}
+		rules[rule.ID] = *rule
+	}
+	ac.configured.Store(&rules)
+	return nil
+}
+
+// newAlertRule validates rule and normalises its symbol
+func newAlertRule(rule alerts.Rule) (*alerts.Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	symbol, err := normaliseSymbol(rule.Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", alerts.ErrInvalidRule, err)
+	}
+	rule.Symbol = symbol
+	return &rule, nil
+}
+
+// Rules returns all alert rules ordered by ID
+func (ac *AlertController) Rules(ctx context.Context) []*AlertRule {
+	configured := *ac.configured.Load()
+	rules := []*AlertRule{}
+	for id, rule := range ac.editable.all(ctx) {
+		if _, ok := configured[id]; !ok {
+			rules = append(rules, &AlertRule{Rule: rule})
+		}
+	}
+	for _, rule := range configured {
+		rules = append(rules, &AlertRule{Rule: rule, ReadOnly: true})
+	}
+	slices.SortFunc(rules, func(a, b *AlertRule) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return rules
+}
+
+// SaveRule creates or replaces the rule with the given ID
+func (ac *AlertController) SaveRule(ctx context.Context, id string, rule alerts.Rule) (*AlertRule, error) {
+	if _, ok := (*ac.configured.Load())[id]; ok {
+		return nil, ErrAlertRuleReadOnly
+	}
+	rule.ID = id
+	validRule, err := newAlertRule(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ac.editable.update(ctx, func(rules map[string]alerts.Rule) error {
+		rules[id] = *validRule
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AlertRule{Rule: *validRule}, nil
+}
+
+func (ac *AlertController) DeleteRule(ctx context.Context, id string) error {
+	if _, ok := (*ac.configured.Load())[id]; ok {
+		return ErrAlertRuleReadOnly
+	}
+
+	return ac.editable.update(ctx, func(rules map[string]alerts.Rule) error {
+		if _, ok := rules[id]; !ok {
+			return ErrAlertRuleNotFound
+		}
+		delete(rules, id)
+		return nil
+	})
+}
+
+// Run evaluates every rule immediately and then every interval until ctx is cancelled
+func (ac *AlertController) Run(ctx context.Context) {
+	ticker := time.NewTicker(ac.interval)
+	defer ticker.Stop()
+
+	for {
+		ac.EvaluateAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateAll evaluates the rules for every symbol with at least one rule. Daily data comes from the cache where
+// possible, so this rarely calls the stock data provider
+func (ac *AlertController) EvaluateAll(ctx context.Context) {
+	// Each evaluation is its own trace so the logs of any alerts it fires, including their deliveries, can be correlated
+	ctx, span := tracer.Start(ctx, "EvaluateAlerts", trace.WithNewRoot())
+	defer span.End()
+
+	symbols := []string{}
+	for _, rule := range ac.Rules(ctx) {
+		if !slices.Contains(symbols, rule.Symbol) {
+			symbols = append(symbols, rule.Symbol)
+		}
+	}
+
+	for _, symbol := range symbols {
+		if ctx.Err() != nil {
+			return
+		}
+		stock, err := ac.stockCtrler.dailyStock(ctx, symbol)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("Failed to get %s daily data for alert rules: %v", symbol, err)
+			continue
+		}
+		ac.Evaluate(ctx, symbol, stock)
+	}
+}
+
+// Evaluate checks the rules for symbol against its daily data
+func (ac *AlertController) Evaluate(ctx context.Context, symbol string, stock *stockclient.Stock) {
+	for _, rule := range ac.Rules(ctx) {
+		if rule.Symbol != symbol {
+			continue
+		}
+
+		trigger := rule.Evaluate(stock.DailyData)
+		if trigger == nil || ac.alreadyFired(ctx, rule.ID, trigger.Date) {
+			continue
+		}
+
+		logging.FromContext(ctx).Infof("Alert rule %s fired: %s", rule.ID, trigger.Message)
+		ac.notifier.Notify(ctx, &alerts.Alert{
+			ID:          fmt.Sprintf("%s:%s", rule.ID, trigger.Date.Format(time.DateOnly)),
+			Rule:        rule.Rule,
+			Symbol:      symbol,
+			Date:        trigger.Date,
+			Close:       trigger.Close,
+			Message:     trigger.Message,
+			TriggeredAt: time.Now().UTC(),
+		})
+	}
+}
+
+// alreadyFired records that the rule fired for date, returning true if that had already been recorded
+func (ac *AlertController) alreadyFired(ctx context.Context, ruleID string, date time.Time) bool {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if last, ok := ac.fired[ruleID]; ok && !date.After(last) {
+		return true
+	}
+
+	// The cache is checked so other replicas refreshing the same data don't fire the rule again
+	// TODO: Replicas refreshing at the same moment can both fire. Use SETNX if that matters
+	cacheCtx, cancel := context.WithTimeout(ctx, ac.stockCtrler.cacheTimeout)
+	defer cancel()
+	key := fmt.Sprintf("alert_fired:%s", ruleID)
+	last, err := cachedJSON[time.Time](cacheCtx, ac.cache, key)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Failed to get alert state from cache: %v", err)
+	}
+	if last != nil && !date.After(*last) {
+		ac.fired[ruleID] = *last
+		return true
+	}
+
+	ac.fired[ruleID] = date
+	if err := cacheJSON(cacheCtx, ac.cache, key, date, alertFiredTTL); err != nil {
+		logging.FromContext(ctx).Warnf("Failed to cache alert state: %v", err)
+	}
+	return false
+}
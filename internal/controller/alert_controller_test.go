@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"stockticker/internal/alerts"
+	"stockticker/internal/stockclient"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockNotifier struct {
+	mu     sync.Mutex
+	Alerts []*alerts.Alert
+}
+
+func (n *mockNotifier) Notify(ctx context.Context, alert *alerts.Alert) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Alerts = append(n.Alerts, alert)
+}
+
+func TestAlertController(t *testing.T) {
+	ctx := context.Background()
+	configured := []alerts.Rule{{ID: "msft-below", Symbol: "msft", Type: alerts.Below, Threshold: 92}}
+	stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 2)
+	require.NoError(t, err)
+
+	t.Run("Invalid evaluation interval", func(t *testing.T) {
+		_, err := NewAlertController(stockCtrler, NewMockCacheClient(), &mockNotifier{}, configured, 0)
+		require.Error(t, err)
+	})
+
+	t.Run("Configured rules are normalised and read-only", func(t *testing.T) {
+		alertCtrler, err := NewAlertController(stockCtrler, NewMockCacheClient(), &mockNotifier{}, configured, time.Minute)
+		require.NoError(t, err)
+
+		rules := alertCtrler.Rules(ctx)
+		require.Len(t, rules, 1)
+		require.Equal(t, "MSFT", rules[0].Symbol)
+		require.True(t, rules[0].ReadOnly)
+
+		_, err = alertCtrler.SaveRule(ctx, "msft-below", configured[0])
+		require.ErrorIs(t, err, ErrAlertRuleReadOnly)
+		require.ErrorIs(t, alertCtrler.DeleteRule(ctx, "msft-below"), ErrAlertRuleReadOnly)
+	})
+
+	t.Run("Invalid configured rules", func(t *testing.T) {
+		for _, rules := range [][]alerts.Rule{
+			{{ID: "bad", Symbol: "MS FT", Type: alerts.Above, Threshold: 1}},
+			{{ID: "bad", Symbol: "MSFT", Type: alerts.Above}},
+			{configured[0], configured[0]},
+		} {
+			_, err := NewAlertController(stockCtrler, NewMockCacheClient(), &mockNotifier{}, rules, time.Minute)
+			require.ErrorIs(t, err, alerts.ErrInvalidRule)
+		}
+	})
+
+	t.Run("Configured rules can be replaced", func(t *testing.T) {
+		alertCtrler, err := NewAlertController(stockCtrler, NewMockCacheClient(), &mockNotifier{}, configured, time.Minute)
+		require.NoError(t, err)
+
+		replacement := alerts.Rule{ID: "nvda-above", Symbol: "NVDA", Type: alerts.Above, Threshold: 100}
+		require.NoError(t, alertCtrler.SetConfigured([]alerts.Rule{replacement}))
+		rules := alertCtrler.Rules(ctx)
+		require.Len(t, rules, 1)
+		require.Equal(t, "nvda-above", rules[0].ID)
+
+		// Nothing changes if any are invalid
+		err = alertCtrler.SetConfigured([]alerts.Rule{configured[0], configured[0]})
+		require.ErrorIs(t, err, alerts.ErrInvalidRule)
+		require.Equal(t, rules, alertCtrler.Rules(ctx))
+	})
+
+	t.Run("Rules can be created, replaced and deleted", func(t *testing.T) {
+		cacheClient := NewMockCacheClient()
+		alertCtrler, err := NewAlertController(stockCtrler, cacheClient, &mockNotifier{}, configured, time.Minute)
+		require.NoError(t, err)
+
+		rule := alerts.Rule{Symbol: "aapl", Type: alerts.Above, Threshold: 200}
+		saved, err := alertCtrler.SaveRule(ctx, "aapl-above", rule)
+		require.NoError(t, err)
+		require.Equal(t, "aapl-above", saved.ID)
+		require.Equal(t, "AAPL", saved.Symbol)
+
+		rule.Threshold = 250
+		_, err = alertCtrler.SaveRule(ctx, "aapl-above", rule)
+		require.NoError(t, err)
+
+		// Other replicas see the same rules via the cache
+		other, err := NewAlertController(stockCtrler, cacheClient, &mockNotifier{}, configured, time.Minute)
+		require.NoError(t, err)
+		rules := other.Rules(ctx)
+		require.Len(t, rules, 2)
+		require.Equal(t, "aapl-above", rules[0].ID)
+		require.Equal(t, float64(250), rules[0].Threshold)
+		require.False(t, rules[0].ReadOnly)
+
+		require.NoError(t, alertCtrler.DeleteRule(ctx, "aapl-above"))
+		require.ErrorIs(t, alertCtrler.DeleteRule(ctx, "aapl-above"), ErrAlertRuleNotFound)
+		require.Len(t, alertCtrler.Rules(ctx), 1)
+	})
+
+	t.Run("Invalid rules are rejected", func(t *testing.T) {
+		alertCtrler, err := NewAlertController(stockCtrler, NewMockCacheClient(), &mockNotifier{}, nil, time.Minute)
+		require.NoError(t, err)
+
+		_, err = alertCtrler.SaveRule(ctx, "Bad ID", alerts.Rule{Symbol: "MSFT", Type: alerts.Above, Threshold: 1})
+		require.ErrorIs(t, err, alerts.ErrInvalidRule)
+	})
+
+	t.Run("Rules fire once per data point", func(t *testing.T) {
+		cacheClient := NewMockCacheClient()
+		notifier := &mockNotifier{}
+		alertCtrler, err := NewAlertController(stockCtrler, cacheClient, notifier, configured, time.Minute)
+		require.NoError(t, err)
+		stock := &stockclient.Stock{DailyData: dailyData}
+
+		alertCtrler.Evaluate(ctx, "AAPL", stock)
+		require.Empty(t, notifier.Alerts)
+
+		alertCtrler.Evaluate(ctx, "MSFT", stock)
+		alertCtrler.Evaluate(ctx, "MSFT", stock)
+		require.Len(t, notifier.Alerts, 1)
+		require.Equal(t, "msft-below:2019-09-20", notifier.Alerts[0].ID)
+		require.Equal(t, 90.35, notifier.Alerts[0].Close)
+
+		// Another replica sharing the cache doesn't fire it again
+		otherNotifier := &mockNotifier{}
+		other, err := NewAlertController(stockCtrler, cacheClient, otherNotifier, configured, time.Minute)
+		require.NoError(t, err)
+		other.Evaluate(ctx, "MSFT", stock)
+		require.Empty(t, otherNotifier.Alerts)
+	})
+
+	t.Run("Rules for every symbol are evaluated", func(t *testing.T) {
+		stockClient := &mockStockClient{StockErrs: map[string]error{"NVDA": fmt.Errorf("rate limited")}}
+		stockCtrler, err := NewStockController(stockClient, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+		notifier := &mockNotifier{}
+		alertCtrler, err := NewAlertController(stockCtrler, NewMockCacheClient(), notifier, configured, time.Minute)
+		require.NoError(t, err)
+		_, err = alertCtrler.SaveRule(ctx, "nvda-above", alerts.Rule{Symbol: "NVDA", Type: alerts.Above, Threshold: 100})
+		require.NoError(t, err)
+
+		alertCtrler.EvaluateAll(ctx)
+		require.Len(t, notifier.Alerts, 1)
+		require.Equal(t, "msft-below", notifier.Alerts[0].Rule.ID)
+	})
+
+	t.Run("Rules are evaluated until stopped", func(t *testing.T) {
+		notifier := &mockNotifier{}
+		alertCtrler, err := NewAlertController(stockCtrler, NewMockCacheClient(), notifier, configured, time.Minute)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			alertCtrler.Run(ctx)
+		}()
+		require.Eventually(t, func() bool {
+			notifier.mu.Lock()
+			defer notifier.mu.Unlock()
+			return len(notifier.Alerts) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("evaluation didn't stop")
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"stockticker/internal/cache"
+	"stockticker/internal/logging"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errInvalidCacheEntry is returned for cached values that can't be decoded, e.g. because they were cached in an
+// earlier format
+var errInvalidCacheEntry = errors.New("invalid cache entry")
+
+// cachedValue is a fetched value as it's cached, along with when it was cached so its age is known
+type cachedValue[T any] struct {
+	CachedAt time.Time `json:"cachedAt"`
+	Value    T         `json:"value"`
+}
+
+// cachedFetch returns the value cached under key. On a cache miss, fetch is called, subject to the controller's
+// upstream rate limit, and its result is cached for ttl. resolution labels the stock client metrics recorded for fetch
+func cachedFetch[T any](ctx context.Context, sc *StockController, key, resolution string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	logger := logging.FromContext(ctx)
+	cacheCtx, cancel := context.WithTimeout(ctx, sc.cacheTimeout)
+	defer cancel()
+	cached, cacheErr := cachedJSON[cachedValue[T]](cacheCtx, sc.cache, key)
+	if cacheErr != nil {
+		logger.Warnf("Failed to get %s from cache: %v", resolution, cacheErr)
+	}
+	// Invalid entries, including those cached before the time was recorded, are replaced
+	if errors.Is(cacheErr, errInvalidCacheEntry) {
+		cacheErr = nil
+	}
+	if cached != nil && !cached.CachedAt.IsZero() {
+		logger.Debugf("Response for %s cached", key)
+		stockCacheEntryAge.WithLabelValues(resolution).Observe(time.Since(cached.CachedAt).Seconds())
+		return cached.Value, nil
+	}
+
+	logger.Debugf("Response for %s not cached", key)
+	// TODO: Distributed rate limiting might be useful here depending on how the third-party implement rate limiting
+	if sc.limiter != nil {
+		if err := sc.limiter.Wait(ctx); err != nil {
+			var zero T
+			return zero, fmt.Errorf("waiting for upstream rate limit: %w", err)
+		}
+	}
+	timer := prometheus.NewTimer(stockClientTimer.WithLabelValues(resolution))
+	val, err := fetch()
+	timer.ObserveDuration()
+	if err != nil {
+		stockClientErrors.WithLabelValues(resolution).Inc()
+		return val, err
+	}
+	lastFetchSuccess.WithLabelValues(resolution).SetToCurrentTime()
+
+	if cacheErr == nil {
+		logger.Debugf("Caching response for %s with TTL: %v", key, ttl)
+		cacheErr = cacheJSON(cacheCtx, sc.cache, key, cachedValue[T]{CachedAt: time.Now(), Value: val}, ttl)
+		if cacheErr != nil {
+			logger.Warnf("Failed to cache %s: %v", resolution, cacheErr)
+		}
+	}
+	return val, nil
+}
+
+// cachedJSON attempts to get a JSON encoded value from cache. A nil value is returned if nothing is cached under key
+func cachedJSON[T any](ctx context.Context, c cache.Client, key string) (_ *T, err error) {
+	ctx, span := tracer.Start(ctx, "cachedJSON", trace.WithAttributes(attribute.String("cache.key", key)))
+	defer endSpan(span, &err)
+
+	timer := prometheus.NewTimer(stockCacheTimer.WithLabelValues("read"))
+	defer timer.ObserveDuration()
+
+	valStr, err := c.Get(ctx, key)
+	if err != nil {
+		stockCacheErrors.WithLabelValues("read").Inc()
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", valStr != ""))
+	if valStr == "" {
+		return nil, nil
+	}
+
+	var val T
+	err = json.Unmarshal([]byte(valStr), &val)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidCacheEntry, err)
+	}
+	return &val, nil
+}
+
+// cacheJSON JSON encodes and caches the provided value
+func cacheJSON(ctx context.Context, c cache.Client, key string, val any, ttl time.Duration) (err error) {
+	ctx, span := tracer.Start(ctx, "cacheJSON", trace.WithAttributes(attribute.String("cache.key", key)))
+	defer endSpan(span, &err)
+
+	timer := prometheus.NewTimer(stockCacheTimer.WithLabelValues("write"))
+	defer timer.ObserveDuration()
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	// TODO: Worth compressing before caching?
+	err = c.Set(ctx, key, string(data), ttl)
+	if err != nil {
+		stockCacheErrors.WithLabelValues("write").Inc()
+		return err
+	}
+
+	return nil
+}
+
+// updateCachedJSON atomically replaces the JSON encoded value cached under key with the result of fn, which is passed
+// the cached value or nil if nothing valid is cached. fn may be called more than once if the value changes
+// concurrently. Errors from fn are returned as they are
+func updateCachedJSON[T any](ctx context.Context, c cache.Client, key string, ttl time.Duration, fn func(cached *T) (T, error)) (err error) {
+	ctx, span := tracer.Start(ctx, "updateCachedJSON", trace.WithAttributes(attribute.String("cache.key", key)))
+	defer endSpan(span, &err)
+
+	timer := prometheus.NewTimer(stockCacheTimer.WithLabelValues("write"))
+	defer timer.ObserveDuration()
+
+	var fnErr error
+	err = c.Update(ctx, key, ttl, func(valStr string) (string, error) {
+		var cached *T
+		var val T
+		// Invalid entries are replaced
+		if valStr != "" && json.Unmarshal([]byte(valStr), &val) == nil {
+			cached = &val
+		}
+		val, fnErr = fn(cached)
+		if fnErr != nil {
+			return "", fnErr
+		}
+		data, err := json.Marshal(val)
+		return string(data), err
+	})
+	if err != nil && fnErr == nil {
+		stockCacheErrors.WithLabelValues("write").Inc()
+	}
+	return err
+}
@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"stockticker/internal/stockclient"
+	"time"
+)
+
+const (
+	maxComparisonSymbols = 10
+	maxComparisonWindow  = 100
+	comparisonBase       = 100
+)
+
+var ErrInvalidComparison = errors.New("invalid comparison")
+
+// ComparisonSeries is a symbol's closes aligned to the comparison's dates
+type ComparisonSeries struct {
+	Symbol string    `json:"symbol"`
+	Closes []float64 `json:"closes"`
+	// Closes rebased so the first date is 100
+	Rebased []float64 `json:"rebased"`
+	// Percentage change over the window
+	Return float64 `json:"return"`
+	// Number of dates without data for the symbol, which use the previous close
+	FilledDays int `json:"filledDays"`
+}
+
+type Comparison struct {
+	// Ordered oldest first
+	Dates  []time.Time         `json:"dates"`
+	Series []*ComparisonSeries `json:"series"`
+	// Pearson correlation of daily returns between each pair of series, ordered as Series. Correlations are nil
+	// where a series doesn't move during the window
+	Correlation [][]*Correlation `json:"correlation"`
+}
+
+type Correlation float64
+
+func (c Correlation) String() string {
+	return fmt.Sprintf("%.2f", float64(c))
+}
+
+type ComparisonController struct {
+	stockCtrler *StockController
+	// Maximum number of symbols fetched at once
+	maxConcurrency int
+}
+
+func NewComparisonController(stockCtrler *StockController, maxConcurrency int) (*ComparisonController, error) {
+	if maxConcurrency <= 0 {
+		return nil, fmt.Errorf("max concurrency must be greater than zero")
+	}
+
+	return &ComparisonController{
+		stockCtrler:    stockCtrler,
+		maxConcurrency: maxConcurrency,
+	}, nil
+}
+
+// Compare aligns the daily closes of symbols over the last window dates on which any of them traded. Dates before
+// every symbol has data are excluded, and gaps after that use the symbol's previous close
+func (cc *ComparisonController) Compare(ctx context.Context, symbols []string, window int) (*Comparison, error) {
+	if len(symbols) < 2 || len(symbols) > maxComparisonSymbols {
+		return nil, fmt.Errorf("%w: between 2 and %d symbols are required", ErrInvalidComparison, maxComparisonSymbols)
+	}
+	if window < 2 || window > maxComparisonWindow {
+		return nil, fmt.Errorf("%w: window must be between 2 and %d days", ErrInvalidComparison, maxComparisonWindow)
+	}
+
+	normalised := []string{}
+	for _, symbol := range symbols {
+		symbol, err := normaliseSymbol(symbol)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(normalised, symbol) {
+			return nil, fmt.Errorf("%w: '%s' is repeated", ErrInvalidComparison, symbol)
+		}
+		normalised = append(normalised, symbol)
+	}
+
+	stocks := make([]*stockclient.Stock, len(normalised))
+	errs := make([]error, len(normalised))
+	forEachConcurrently(normalised, cc.maxConcurrency, func(i int, symbol string) {
+		stocks[i], errs[i] = cc.stockCtrler.dailyStock(ctx, symbol)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve %s: %w", normalised[i], err)
+		}
+		if len(stocks[i].DailyData) == 0 {
+			return nil, fmt.Errorf("no data returned for %s", normalised[i])
+		}
+	}
+
+	dates := alignedDates(stocks, window)
+	if len(dates) < 2 {
+		return nil, fmt.Errorf("%w: the symbols have fewer than 2 dates in common", ErrInvalidComparison)
+	}
+
+	comparison := &Comparison{Dates: dates}
+	for i, stock := range stocks {
+		comparison.Series = append(comparison.Series, alignSeries(normalised[i], stock.DailyData, dates))
+	}
+	comparison.Correlation = correlationMatrix(comparison.Series)
+	return comparison, nil
+}
+
+func (cc *ComparisonController) ViewTemplate() string {
+	return "comparison_view.tmpl"
+}
+
+// alignedDates returns up to the last window dates, oldest first, on which any stock traded from the point every
+// stock has data
+func alignedDates(stocks []*stockclient.Stock, window int) []time.Time {
+	var start time.Time
+	seen := map[time.Time]bool{}
+	for _, stock := range stocks {
+		// Daily data is ordered newest first
+		if oldest := stock.DailyData[len(stock.DailyData)-1].Date; oldest.After(start) {
+			start = oldest
+		}
+		for _, dayData := range stock.DailyData {
+			seen[dayData.Date] = true
+		}
+	}
+
+	dates := []time.Time{}
+	for date := range seen {
+		if !date.Before(start) {
+			dates = append(dates, date)
+		}
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+	return dates[max(0, len(dates)-window):]
+}
+
+// alignSeries returns the closes for each of dates, using the previous close for dates without data
+func alignSeries(symbol string, dailyData []*stockclient.DayData, dates []time.Time) *ComparisonSeries {
+	series := &ComparisonSeries{Symbol: symbol}
+
+	// Walk the daily data oldest first alongside dates
+	i := len(dailyData) - 1
+	prevClose := float64(0)
+	for _, date := range dates {
+		traded := false
+		for ; i >= 0 && !dailyData[i].Date.After(date); i-- {
+			prevClose = dailyData[i].Close
+			traded = dailyData[i].Date.Equal(date)
+		}
+		if !traded {
+			series.FilledDays++
+		}
+		series.Closes = append(series.Closes, prevClose)
+	}
+
+	base := series.Closes[0]
+	for _, price := range series.Closes {
+		rebased := float64(0)
+		if base != 0 {
+			rebased = price / base * comparisonBase
+		}
+		series.Rebased = append(series.Rebased, rebased)
+	}
+	series.Return = series.Rebased[len(series.Rebased)-1] - comparisonBase
+	return series
+}
+
+func correlationMatrix(series []*ComparisonSeries) [][]*Correlation {
+	returns := make([][]float64, len(series))
+	for i, s := range series {
+		returns[i] = dailyReturns(s.Closes)
+	}
+
+	matrix := make([][]*Correlation, len(series))
+	for i := range series {
+		matrix[i] = make([]*Correlation, len(series))
+		for j := range series {
+			if corr, ok := correlation(returns[i], returns[j]); ok {
+				c := Correlation(corr)
+				matrix[i][j] = &c
+			}
+		}
+	}
+	return matrix
+}
+
+func dailyReturns(closes []float64) []float64 {
+	returns := make([]float64, 0, len(closes)-1)
+	for i := 1; i < len(closes); i++ {
+		r := float64(0)
+		if closes[i-1] != 0 {
+			r = closes[i]/closes[i-1] - 1
+		}
+		returns = append(returns, r)
+	}
+	return returns
+}
+
+// correlation returns the Pearson correlation coefficient of x and y, which must be the same length. false is
+// returned if either has no variance
+func correlation(x, y []float64) (float64, bool) {
+	n := float64(len(x))
+	if n == 0 {
+		return 0, false
+	}
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varX*varY), true
+}
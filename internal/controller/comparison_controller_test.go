@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"stockticker/internal/stockclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// dailyCloses returns daily data sorted newest first for closes keyed by day of October 2024
+func dailyCloses(closes map[int]float64) []*stockclient.DayData {
+	dailyData := []*stockclient.DayData{}
+	for day := 31; day > 0; day-- {
+		if c, ok := closes[day]; ok {
+			dailyData = append(dailyData, &stockclient.DayData{Date: time.Date(2024, 10, day, 0, 0, 0, 0, time.UTC), Close: c})
+		}
+	}
+	return dailyData
+}
+
+func TestComparisonController(t *testing.T) {
+	ctx := context.Background()
+	stockClient := &mockStockClient{Stocks: map[string][]*stockclient.DayData{
+		"SPY":  dailyCloses(map[int]float64{1: 500, 2: 505, 3: 510, 4: 500, 7: 520}),
+		"MSFT": dailyCloses(map[int]float64{2: 400, 3: 404, 4: 392, 7: 416}),
+		// Missing the 4th and moves opposite to SPY
+		"GLD":  dailyCloses(map[int]float64{1: 200, 2: 198, 3: 196, 7: 190}),
+		"CASH": dailyCloses(map[int]float64{2: 1, 3: 1, 4: 1, 7: 1}),
+	}}
+	stockCtrler, err := NewStockController(stockClient, NewMockCacheClient(), "MSFT", 2)
+	require.NoError(t, err)
+	comparisonCtrler, err := NewComparisonController(stockCtrler, 2)
+	require.NoError(t, err)
+
+	t.Run("Series are aligned and rebased", func(t *testing.T) {
+		comparison, err := comparisonCtrler.Compare(ctx, []string{"spy", "msft", "gld"}, 10)
+		require.NoError(t, err)
+
+		// MSFT has no data on the 1st so the comparison starts on the 2nd
+		require.Len(t, comparison.Dates, 4)
+		require.Equal(t, time.Date(2024, 10, 2, 0, 0, 0, 0, time.UTC), comparison.Dates[0])
+		require.Equal(t, time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC), comparison.Dates[3])
+
+		spy, msft, gld := comparison.Series[0], comparison.Series[1], comparison.Series[2]
+		require.Equal(t, "SPY", spy.Symbol)
+		require.Equal(t, []float64{505, 510, 500, 520}, spy.Closes)
+		require.Equal(t, float64(100), msft.Rebased[0])
+		require.InDelta(t, 104, msft.Rebased[3], 0.0001)
+		require.InDelta(t, 4, msft.Return, 0.0001)
+
+		require.Equal(t, []float64{198, 196, 196, 190}, gld.Closes)
+		require.Equal(t, 1, gld.FilledDays)
+		require.Zero(t, spy.FilledDays)
+	})
+
+	t.Run("Correlation of daily returns", func(t *testing.T) {
+		comparison, err := comparisonCtrler.Compare(ctx, []string{"SPY", "MSFT", "CASH"}, 10)
+		require.NoError(t, err)
+
+		require.InDelta(t, 1, float64(*comparison.Correlation[0][0]), 0.0001)
+		require.Greater(t, float64(*comparison.Correlation[0][1]), 0.9)
+		require.Equal(t, comparison.Correlation[0][1], comparison.Correlation[1][0])
+		// CASH doesn't move so its correlation is undefined
+		require.Nil(t, comparison.Correlation[0][2])
+		require.Nil(t, comparison.Correlation[2][2])
+	})
+
+	t.Run("Window limits the number of dates", func(t *testing.T) {
+		comparison, err := comparisonCtrler.Compare(ctx, []string{"SPY", "GLD"}, 2)
+		require.NoError(t, err)
+		require.Len(t, comparison.Dates, 2)
+		require.Equal(t, time.Date(2024, 10, 4, 0, 0, 0, 0, time.UTC), comparison.Dates[0])
+		// GLD has no data on the 4th so it uses the previous close
+		require.Equal(t, []float64{196, 190}, comparison.Series[1].Closes)
+	})
+
+	t.Run("Invalid comparisons", func(t *testing.T) {
+		for _, test := range []struct {
+			symbols []string
+			window  int
+		}{
+			{[]string{"SPY"}, 10},
+			{[]string{"SPY", "spy"}, 10},
+			{[]string{"SPY", "MSFT"}, 1},
+			{[]string{"SPY", "MSFT"}, maxComparisonWindow + 1},
+		} {
+			_, err := comparisonCtrler.Compare(ctx, test.symbols, test.window)
+			require.ErrorIs(t, err, ErrInvalidComparison)
+		}
+
+		_, err := comparisonCtrler.Compare(ctx, []string{"SPY", "MS FT"}, 10)
+		require.ErrorIs(t, err, ErrInvalidSymbol)
+	})
+}
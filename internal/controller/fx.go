@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"stockticker/internal/stockclient"
+	"strings"
+	"time"
+)
+
+const defaultSymbolCurrency = "USD"
+
+var (
+	ErrInvalidCurrency = errors.New("invalid currency")
+
+	currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	// Currencies some exchanges quote prices in that are a fraction of another currency, e.g. pence on the LSE
+	minorCurrencies = map[string]struct {
+		major  string
+		factor float64
+	}{
+		"GBX": {"GBP", 0.01},
+	}
+)
+
+// WithFXProvider enables converting prices to other currencies
+func WithFXProvider(fx stockclient.FXProvider) StockControllerOption {
+	return func(sc *StockController) {
+		sc.fx = fx
+	}
+}
+
+// convertPrices converts dailyData to currency using the exchange rate on each date unless currency is empty
+func (sc *StockController) convertPrices(ctx context.Context, symbol string, dailyData []*stockclient.DayData, currency string) (*DailyPrices, error) {
+	prices := &DailyPrices{Symbol: symbol, DailyData: dailyData}
+	if currency == "" {
+		return prices, nil
+	}
+
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if !currencyRegexp.MatchString(currency) {
+		return nil, fmt.Errorf("%w: '%s' must be a three letter code", ErrInvalidCurrency, currency)
+	}
+	if sc.fx == nil {
+		return nil, fmt.Errorf("currency conversion isn't enabled")
+	}
+
+	original, err := sc.symbolCurrency(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	prices.Currency = currency
+	prices.OriginalCurrency = original
+
+	// Minor currencies are converted to their major currency first
+	from, factor := original, float64(1)
+	if minor, ok := minorCurrencies[original]; ok {
+		from, factor = minor.major, minor.factor
+	}
+	if from == currency && factor == 1 {
+		return prices, nil
+	}
+
+	rates := []*stockclient.FXRate{{Rate: 1}}
+	if from != currency {
+		rates, err = sc.fxRates(ctx, from, currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Both daily data and rates are ordered newest first. Dates without a rate, e.g. holidays, use the previous rate
+	converted := make([]*stockclient.DayData, 0, len(dailyData))
+	i := 0
+	for _, dayData := range dailyData {
+		for i < len(rates) && rates[i].Date.After(dayData.Date) {
+			i++
+		}
+		if i == len(rates) {
+			return nil, fmt.Errorf("no %s to %s rate available for %s", from, currency, dayData.Date.Format(time.DateOnly))
+		}
+		rate := factor * rates[i].Rate
+		c := *dayData
+		c.Close *= rate
+		c.AdjustedClose *= rate
+		c.Dividend *= rate
+		converted = append(converted, &c)
+	}
+	prices.DailyData = converted
+	prices.Converted = true
+	return prices, nil
+}
+
+// symbolCurrency looks up the currency symbol is priced in, assuming USD if the symbol search doesn't know it
+func (sc *StockController) symbolCurrency(ctx context.Context, symbol string) (string, error) {
+	// Pairs are priced in their quote currency
+	if class, rest := stockclient.ParseSymbol(symbol); class != stockclient.Equity {
+		_, quote, _ := stockclient.SplitPair(rest)
+		return quote, nil
+	}
+
+	result, err := sc.SearchSymbols(ctx, symbol)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up currency of %s: %w", symbol, err)
+	}
+	for _, m := range result.Matches {
+		if strings.EqualFold(m.Symbol, symbol) && m.Currency != "" {
+			return strings.ToUpper(m.Currency), nil
+		}
+	}
+	return defaultSymbolCurrency, nil
+}
+
+// fxRates returns the daily rates for converting from to to, from cache where possible
+func (sc *StockController) fxRates(ctx context.Context, from, to string) ([]*stockclient.FXRate, error) {
+	return cachedFetch(ctx, sc, fmt.Sprintf("fx:%s:%s", from, to), "fx_daily", cacheTTL(), func() ([]*stockclient.FXRate, error) {
+		return sc.fx.FXDaily(ctx, from, to)
+	})
+}
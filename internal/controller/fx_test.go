@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"context"
+	"stockticker/internal/stockclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyPrices(t *testing.T) {
+	ctx := context.Background()
+	newStockCtrler := func(t *testing.T, stockClient *mockStockClient) *StockController {
+		stockCtrler, err := NewStockController(stockClient, NewMockCacheClient(), "MSFT", 2, WithFXProvider(stockClient))
+		require.NoError(t, err)
+		return stockCtrler
+	}
+	fxRates := map[string][]*stockclient.FXRate{
+		"USD:EUR": {
+			{Date: time.Date(2019, 9, 20, 0, 0, 0, 0, time.UTC), Rate: 0.9},
+			{Date: time.Date(2019, 9, 18, 0, 0, 0, 0, time.UTC), Rate: 0.8},
+		},
+		"GBP:EUR": {
+			{Date: time.Date(2019, 9, 19, 0, 0, 0, 0, time.UTC), Rate: 1.1},
+		},
+	}
+
+	t.Run("Prices aren't converted without a currency", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		prices, err := newStockCtrler(t, stockClient).DailyPrices(ctx, "msft", PriceOptions{})
+		require.NoError(t, err)
+		require.Equal(t, &DailyPrices{Symbol: "MSFT", DailyData: dailyData}, prices)
+		require.Zero(t, stockClient.FXCalls)
+	})
+
+	t.Run("Prices are converted using the rate on each date", func(t *testing.T) {
+		stockClient := &mockStockClient{FXRates: fxRates}
+		stockCtrler := newStockCtrler(t, stockClient)
+
+		prices, err := stockCtrler.DailyPrices(ctx, "MSFT", PriceOptions{Currency: "eur"})
+		require.NoError(t, err)
+		require.Equal(t, "EUR", prices.Currency)
+		require.Equal(t, "USD", prices.OriginalCurrency)
+		require.True(t, prices.Converted)
+		require.Len(t, prices.DailyData, 2)
+		require.InDelta(t, 90.35*0.9, prices.DailyData[0].Close, 0.0001)
+		// There's no rate on the 19th so the previous rate is used
+		require.InDelta(t, 94.4*0.8, prices.DailyData[1].Close, 0.0001)
+		// Cached daily data isn't modified
+		require.Equal(t, 90.35, dailyData[0].Close)
+
+		_, err = stockCtrler.DailyPrices(ctx, "MSFT", PriceOptions{Currency: "EUR"})
+		require.NoError(t, err)
+		require.Equal(t, 1, stockClient.FXCalls)
+	})
+
+	t.Run("Minor currencies are converted via their major currency", func(t *testing.T) {
+		stockClient := &mockStockClient{FXRates: fxRates}
+		stockCtrler := newStockCtrler(t, stockClient)
+
+		prices, err := stockCtrler.DailyPrices(ctx, "TSCO.LON", PriceOptions{Currency: "EUR"})
+		require.NoError(t, err)
+		require.Equal(t, "GBX", prices.OriginalCurrency)
+		require.InDelta(t, 90.35*0.01*1.1, prices.DailyData[0].Close, 0.0001)
+
+		prices, err = stockCtrler.DailyPrices(ctx, "TSCO.LON", PriceOptions{Currency: "GBP"})
+		require.NoError(t, err)
+		require.True(t, prices.Converted)
+		require.InDelta(t, 0.9035, prices.DailyData[0].Close, 0.0001)
+		require.Equal(t, 1, stockClient.FXCalls)
+	})
+
+	t.Run("Prices in the requested currency aren't converted", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		prices, err := newStockCtrler(t, stockClient).DailyPrices(ctx, "MSFT", PriceOptions{Currency: "USD"})
+		require.NoError(t, err)
+		require.False(t, prices.Converted)
+		require.Equal(t, "USD", prices.Currency)
+		require.Equal(t, dailyData, prices.DailyData)
+		require.Zero(t, stockClient.FXCalls)
+	})
+
+	t.Run("Dates before the first rate can't be converted", func(t *testing.T) {
+		stockClient := &mockStockClient{FXRates: map[string][]*stockclient.FXRate{"USD:EUR": fxRates["USD:EUR"][:1]}}
+		_, err := newStockCtrler(t, stockClient).DailyPrices(ctx, "MSFT", PriceOptions{Currency: "EUR"})
+		require.ErrorContains(t, err, "no USD to EUR rate available for 2019-09-19")
+	})
+
+	t.Run("Pairs are converted from their quote currency", func(t *testing.T) {
+		stockClient := &mockStockClient{FXRates: fxRates}
+		prices, err := newStockCtrler(t, stockClient).DailyPrices(ctx, "crypto:btc-usd", PriceOptions{Currency: "EUR"})
+		require.NoError(t, err)
+		require.Equal(t, "crypto:BTC-USD", prices.Symbol)
+		require.Equal(t, "USD", prices.OriginalCurrency)
+		require.InDelta(t, 90.35*0.9, prices.DailyData[0].Close, 0.0001)
+		require.Empty(t, stockClient.Keywords)
+	})
+
+	t.Run("Invalid currency", func(t *testing.T) {
+		_, err := newStockCtrler(t, &mockStockClient{}).DailyPrices(ctx, "MSFT", PriceOptions{Currency: "EURO"})
+		require.ErrorIs(t, err, ErrInvalidCurrency)
+	})
+
+	t.Run("Stock view data indicates the original currency", func(t *testing.T) {
+		viewData, err := newStockCtrler(t, &mockStockClient{FXRates: fxRates}).Stock(ctx, PriceOptions{Currency: "EUR"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "EUR", viewData["currency"])
+		require.Equal(t, "USD", viewData["originalCurrency"])
+		require.InDelta(t, (90.35*0.9+94.4*0.8)/2, viewData["avgClose"], 0.0001)
+	})
+}
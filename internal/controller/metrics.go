@@ -51,4 +51,39 @@ var (
 		},
 		[]string{"operation"},
 	)
+
+	// Timestamps rather than ages are reported so they don't go stale between updates. Ages are worked out in queries,
+	// e.g. time() - stockticker_stock_controller_newest_data_timestamp_seconds
+	newestDataTimestamp = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "stockticker",
+			Subsystem: "stock_controller",
+			Name:      "newest_data_timestamp_seconds",
+			Help:      "Date of the newest daily data point served for each symbol as a Unix timestamp",
+		},
+		[]string{"symbol"},
+	)
+
+	lastFetchSuccess = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "stockticker",
+			Subsystem: "stock_controller",
+			Name:      "last_fetch_success_timestamp_seconds",
+			Help:      "Time of the last successful request to the stock client as a Unix timestamp",
+		},
+		[]string{"resolution"},
+	)
+
+	stockCacheEntryAge = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "stockticker",
+			Subsystem: "stock_controller",
+			Name:      "stock_cache_entry_age_seconds",
+			Help:      "Bucketed histogram of the age of cached responses when they're read",
+
+			// 1m to 2.8d
+			Buckets: prometheus.ExponentialBuckets(60, 4, 7),
+		},
+		[]string{"resolution"},
+	)
 )
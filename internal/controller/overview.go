@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"stockticker/internal/stockclient"
+	"time"
+)
+
+const (
+	// Fundamentals change at most quarterly
+	defaultOverviewTTL = 24 * time.Hour
+)
+
+// Overview returns company information for symbol
+func (sc *StockController) Overview(ctx context.Context, symbol string) (*stockclient.Overview, error) {
+	symbol, err := normaliseSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if class, _ := stockclient.ParseSymbol(symbol); class != stockclient.Equity {
+		return nil, fmt.Errorf("overviews are %w '%s'", stockclient.ErrUnsupportedAssetClass, class)
+	}
+
+	return cachedFetch(ctx, sc, fmt.Sprintf("overview:%s", symbol), "overview", sc.settings.Load().ttls.Overview, func() (*stockclient.Overview, error) {
+		return sc.client.Overview(ctx, symbol)
+	})
+}
+
+// humaniseAmount formats large amounts such as market capitalisations, e.g. 3.12T
+func humaniseAmount(amount int64) string {
+	value := float64(amount)
+	for _, unit := range []struct {
+		suffix string
+		size   float64
+	}{{"T", 1e12}, {"B", 1e9}, {"M", 1e6}} {
+		if value >= unit.size {
+			return fmt.Sprintf("%.2f%s", value/unit.size, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%d", amount)
+}
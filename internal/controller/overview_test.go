@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"stockticker/internal/stockclient"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverview(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Overviews are cached", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient := NewMockCacheClient()
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		result, err := stockCtrler.Overview(ctx, " msft")
+		require.NoError(t, err)
+		require.Equal(t, overview, result)
+		require.Equal(t, "MSFT", stockClient.OverviewSymbol)
+		require.Contains(t, cacheClient.Cache, "overview:MSFT")
+
+		_, err = stockCtrler.Overview(ctx, "MSFT")
+		require.NoError(t, err)
+		require.Equal(t, 1, stockClient.Calls)
+	})
+
+	t.Run("Overviews are only available for equities", func(t *testing.T) {
+		stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+
+		_, err = stockCtrler.Overview(ctx, "fx:EUR-USD")
+		require.ErrorIs(t, err, stockclient.ErrUnsupportedAssetClass)
+	})
+
+	t.Run("Stock view data includes the overview", func(t *testing.T) {
+		stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+
+		viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, overview, viewData["overview"])
+		require.Equal(t, "3.11T", viewData["marketCap"])
+	})
+}
+
+func TestHumaniseAmount(t *testing.T) {
+	require.Equal(t, "3.11T", humaniseAmount(3108224205000))
+	require.Equal(t, "212.90B", humaniseAmount(212903313000))
+	require.Equal(t, "1.50M", humaniseAmount(1500000))
+	require.Equal(t, "999999", humaniseAmount(999999))
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"stockticker/internal/logging"
+	"stockticker/internal/portfolio"
+	"strings"
+	"time"
+)
+
+// PositionValuation values a single position at its latest close. PositionValue is nil and Error is set if the
+// symbol's data couldn't be retrieved
+type PositionValuation struct {
+	portfolio.Position
+	*PositionValue
+	Error string `json:"error,omitempty"`
+}
+
+type PositionValue struct {
+	Date                 time.Time `json:"date"`
+	Price                float64   `json:"price"`
+	MarketValue          float64   `json:"marketValue"`
+	UnrealisedPnL        float64   `json:"unrealisedPnl"`
+	UnrealisedPnLPercent float64   `json:"unrealisedPnlPercent"`
+	DayChange            float64   `json:"dayChange"`
+	DayChangePercent     float64   `json:"dayChangePercent"`
+	// Percentage of the market value of all positions in the same currency
+	Weight float64 `json:"weight"`
+}
+
+// PortfolioTotal sums the positions in a single currency
+type PortfolioTotal struct {
+	Currency             string  `json:"currency"`
+	MarketValue          float64 `json:"marketValue"`
+	CostBasis            float64 `json:"costBasis"`
+	UnrealisedPnL        float64 `json:"unrealisedPnl"`
+	UnrealisedPnLPercent float64 `json:"unrealisedPnlPercent"`
+	DayChange            float64 `json:"dayChange"`
+	DayChangePercent     float64 `json:"dayChangePercent"`
+	// Set if any positions in the currency couldn't be valued and so are missing from the total
+	Incomplete bool `json:"incomplete"`
+}
+
+type Valuation struct {
+	Positions []*PositionValuation `json:"positions"`
+	// Ordered by currency
+	Totals []*PortfolioTotal `json:"totals"`
+}
+
+type PortfolioController struct {
+	stockCtrler *StockController
+	positions   []portfolio.Position
+	// Maximum number of symbols fetched at once when valuing the portfolio
+	maxConcurrency int
+}
+
+func NewPortfolioController(stockCtrler *StockController, positions []portfolio.Position, maxConcurrency int) (*PortfolioController, error) {
+	if maxConcurrency <= 0 {
+		return nil, fmt.Errorf("max concurrency must be greater than zero")
+	}
+
+	pc := &PortfolioController{
+		stockCtrler:    stockCtrler,
+		positions:      []portfolio.Position{},
+		maxConcurrency: maxConcurrency,
+	}
+	for _, position := range positions {
+		if err := position.Validate(); err != nil {
+			return nil, err
+		}
+		symbol, err := normaliseSymbol(position.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", portfolio.ErrInvalidPosition, err)
+		}
+		position.Symbol = symbol
+		pc.positions = append(pc.positions, position)
+	}
+	return pc, nil
+}
+
+// Valuation values each position at its symbol's latest close. Prices come from the same cached daily data as the
+// rest of the controller so valuing the portfolio rarely calls the stock data provider
+func (pc *PortfolioController) Valuation(ctx context.Context) *Valuation {
+	symbols := make([]string, len(pc.positions))
+	for i, position := range pc.positions {
+		symbols[i] = position.Symbol
+	}
+	summaries, errs := pc.stockCtrler.Summaries(ctx, symbols, pc.maxConcurrency)
+
+	valuation := &Valuation{
+		Positions: make([]*PositionValuation, len(pc.positions)),
+		Totals:    []*PortfolioTotal{},
+	}
+	totals := map[string]*PortfolioTotal{}
+	for i, position := range pc.positions {
+		total, ok := totals[position.Currency]
+		if !ok {
+			total = &PortfolioTotal{Currency: position.Currency}
+			totals[position.Currency] = total
+			valuation.Totals = append(valuation.Totals, total)
+		}
+
+		pv := &PositionValuation{Position: position}
+		valuation.Positions[i] = pv
+		if errs[i] != nil {
+			logging.FromContext(ctx).Warnf("Failed to value %s for portfolio: %v", position.Symbol, errs[i])
+			pv.Error = "failed to retrieve stock data"
+			total.Incomplete = true
+			continue
+		}
+
+		summary := summaries[i]
+		pv.PositionValue = &PositionValue{}
+		pv.Date = summary.Date
+		pv.Price = summary.LastClose
+		pv.MarketValue = position.Quantity * summary.LastClose
+		pv.UnrealisedPnL = pv.MarketValue - position.CostBasis
+		pv.UnrealisedPnLPercent = percentOf(pv.UnrealisedPnL, position.CostBasis)
+		pv.DayChange = position.Quantity * summary.Change
+		pv.DayChangePercent = summary.ChangePercent
+
+		total.MarketValue += pv.MarketValue
+		total.CostBasis += position.CostBasis
+		total.UnrealisedPnL += pv.UnrealisedPnL
+		total.DayChange += pv.DayChange
+	}
+
+	for _, total := range valuation.Totals {
+		total.UnrealisedPnLPercent = percentOf(total.UnrealisedPnL, total.CostBasis)
+		total.DayChangePercent = percentOf(total.DayChange, total.MarketValue-total.DayChange)
+	}
+	for _, pv := range valuation.Positions {
+		if pv.PositionValue != nil {
+			pv.Weight = percentOf(pv.MarketValue, totals[pv.Currency].MarketValue)
+		}
+	}
+	slices.SortFunc(valuation.Totals, func(a, b *PortfolioTotal) int {
+		return strings.Compare(a.Currency, b.Currency)
+	})
+	return valuation
+}
+
+func (pc *PortfolioController) ViewTemplate() string {
+	return "portfolio_view.tmpl"
+}
+
+// percentOf returns value as a percentage of total, or zero if total is zero
+func percentOf(value, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total * 100
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"stockticker/internal/portfolio"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioController(t *testing.T) {
+	ctx := context.Background()
+
+	newPortfolioCtrler := func(t *testing.T, stockClient *mockStockClient, positions []portfolio.Position) *PortfolioController {
+		stockCtrler, err := NewStockController(stockClient, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+		portfolioCtrler, err := NewPortfolioController(stockCtrler, positions, 2)
+		require.NoError(t, err)
+		return portfolioCtrler
+	}
+
+	t.Run("Invalid positions", func(t *testing.T) {
+		stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+
+		for _, position := range []portfolio.Position{
+			{Symbol: "MS FT", Quantity: 1},
+			{Symbol: "MSFT", Quantity: 0},
+			{Symbol: "MSFT", Quantity: 1, CostBasis: -1},
+			{Symbol: "MSFT", Quantity: 1, Currency: "dollars"},
+		} {
+			_, err = NewPortfolioController(stockCtrler, []portfolio.Position{position}, 2)
+			require.ErrorIs(t, err, portfolio.ErrInvalidPosition)
+		}
+	})
+
+	t.Run("Positions are valued at the latest close", func(t *testing.T) {
+		portfolioCtrler := newPortfolioCtrler(t, &mockStockClient{}, []portfolio.Position{
+			{Symbol: "msft", Quantity: 10, CostBasis: 1000},
+			{Symbol: "AAPL", Quantity: 30, CostBasis: 2000},
+			{Symbol: "TSCO.LON", Quantity: 5, CostBasis: 500, Currency: "gbp"},
+		})
+
+		valuation := portfolioCtrler.Valuation(ctx)
+		require.Len(t, valuation.Positions, 3)
+
+		msft := valuation.Positions[0]
+		require.Equal(t, "MSFT", msft.Symbol)
+		require.Equal(t, "USD", msft.Currency)
+		require.Equal(t, 90.35, msft.Price)
+		require.InDelta(t, 903.5, msft.MarketValue, 0.001)
+		require.InDelta(t, -96.5, msft.UnrealisedPnL, 0.001)
+		require.InDelta(t, -9.65, msft.UnrealisedPnLPercent, 0.001)
+		require.InDelta(t, -40.5, msft.DayChange, 0.001)
+		require.InDelta(t, 25, msft.Weight, 0.001)
+		require.InDelta(t, 75, valuation.Positions[1].Weight, 0.001)
+		require.InDelta(t, 100, valuation.Positions[2].Weight, 0.001)
+
+		// Totals are per currency as prices in different currencies can't be summed
+		require.Len(t, valuation.Totals, 2)
+		require.Equal(t, "GBP", valuation.Totals[0].Currency)
+		usd := valuation.Totals[1]
+		require.Equal(t, "USD", usd.Currency)
+		require.InDelta(t, 3614, usd.MarketValue, 0.001)
+		require.Equal(t, float64(3000), usd.CostBasis)
+		require.InDelta(t, 614, usd.UnrealisedPnL, 0.001)
+		require.InDelta(t, -162, usd.DayChange, 0.001)
+		require.InDelta(t, -162/(3614+162.0)*100, usd.DayChangePercent, 0.001)
+		require.False(t, usd.Incomplete)
+	})
+
+	t.Run("Positions that can't be valued are excluded from totals", func(t *testing.T) {
+		stockClient := &mockStockClient{StockErrs: map[string]error{"AAPL": fmt.Errorf("rate limited")}}
+		portfolioCtrler := newPortfolioCtrler(t, stockClient, []portfolio.Position{
+			{Symbol: "MSFT", Quantity: 10, CostBasis: 1000},
+			{Symbol: "AAPL", Quantity: 30, CostBasis: 2000},
+		})
+
+		valuation := portfolioCtrler.Valuation(ctx)
+		require.Equal(t, "failed to retrieve stock data", valuation.Positions[1].Error)
+		require.Nil(t, valuation.Positions[1].PositionValue)
+		require.InDelta(t, 100, valuation.Positions[0].Weight, 0.001)
+		require.InDelta(t, 903.5, valuation.Totals[0].MarketValue, 0.001)
+		require.True(t, valuation.Totals[0].Incomplete)
+	})
+
+	t.Run("Zero values are serialised and unknown values aren't", func(t *testing.T) {
+		stockClient := &mockStockClient{StockErrs: map[string]error{"AAPL": fmt.Errorf("rate limited")}}
+		portfolioCtrler := newPortfolioCtrler(t, stockClient, []portfolio.Position{
+			{Symbol: "MSFT", Quantity: 10},
+			{Symbol: "AAPL", Quantity: 30, CostBasis: 2000},
+		})
+
+		valuation := portfolioCtrler.Valuation(ctx)
+		valued, err := json.Marshal(valuation.Positions[0])
+		require.NoError(t, err)
+		require.Contains(t, string(valued), `"unrealisedPnlPercent":0,`)
+		failed, err := json.Marshal(valuation.Positions[1])
+		require.NoError(t, err)
+		require.JSONEq(t, `{"symbol":"AAPL","quantity":30,"costBasis":2000,"currency":"USD","error":"failed to retrieve stock data"}`, string(failed))
+	})
+
+	t.Run("Empty portfolio", func(t *testing.T) {
+		valuation := newPortfolioCtrler(t, &mockStockClient{}, nil).Valuation(ctx)
+		require.Empty(t, valuation.Positions)
+		require.Empty(t, valuation.Totals)
+	})
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"stockticker/internal/stockclient"
+)
+
+// PriceOptions control how daily closes are presented
+type PriceOptions struct {
+	// Converts closes to this currency if set
+	Currency string
+	// Uses closes adjusted for splits and dividends
+	Adjusted bool
+}
+
+// DailyPrices is a symbol's most recent daily closes, adjusted and converted to Currency if requested
+type DailyPrices struct {
+	Symbol string `json:"symbol"`
+	// The currency of the closes
+	Currency string `json:"currency,omitempty"`
+	// The currency the symbol is priced in. Only set when converting as it has to be looked up
+	OriginalCurrency string `json:"originalCurrency,omitempty"`
+	Converted        bool   `json:"converted"`
+	// Closes are adjusted for splits and dividends, which are included on the dates they occurred
+	Adjusted  bool                   `json:"adjusted"`
+	DailyData []*stockclient.DayData `json:"dailyData"`
+}
+
+// DailyPrices returns the closes for the configured number of days according to opts
+func (sc *StockController) DailyPrices(ctx context.Context, symbol string, opts PriceOptions) (*DailyPrices, error) {
+	symbol, err := normaliseSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return sc.prices(ctx, symbol, opts, 0)
+}
+
+// prices returns the closes for the configured number of days plus up to lookback earlier days
+func (sc *StockController) prices(ctx context.Context, symbol string, opts PriceOptions, lookback int) (*DailyPrices, error) {
+	var stock *stockclient.Stock
+	var err error
+	if opts.Adjusted {
+		stock, err = sc.adjustedStock(ctx, symbol)
+	} else {
+		stock, err = sc.dailyStock(ctx, symbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dailyData := stock.DailyData[:min(sc.settings.Load().numDays+lookback, len(stock.DailyData))]
+	if opts.Adjusted {
+		dailyData = adjustedCloses(dailyData)
+	}
+	prices, err := sc.convertPrices(ctx, symbol, dailyData, opts.Currency)
+	if err != nil {
+		return nil, err
+	}
+	prices.Adjusted = opts.Adjusted
+	return prices, nil
+}
+
+// adjustedStock returns the daily data including adjusted closes for symbol, from cache where possible
+func (sc *StockController) adjustedStock(ctx context.Context, symbol string) (*stockclient.Stock, error) {
+	if class, _ := stockclient.ParseSymbol(symbol); class != stockclient.Equity {
+		return nil, fmt.Errorf("adjusted prices are %w '%s'", stockclient.ErrUnsupportedAssetClass, class)
+	}
+
+	stock, err := cachedFetch(ctx, sc, fmt.Sprintf("symbol_adjusted:%s", symbol), "daily_adjusted", cacheTTL(), func() (*stockclient.Stock, error) {
+		return sc.client.AdjustedStock(ctx, symbol, stockclient.Ascending)
+	})
+	if err != nil {
+		return nil, err
+	}
+	sc.recordNewestData(symbol, stock)
+	return stock, nil
+}
+
+// adjustedCloses returns copies of dailyData with the adjusted closes in place of the raw closes so everything
+// downstream, e.g. averages, uses them
+func adjustedCloses(dailyData []*stockclient.DayData) []*stockclient.DayData {
+	adjusted := make([]*stockclient.DayData, 0, len(dailyData))
+	for _, dayData := range dailyData {
+		a := *dayData
+		a.Close = a.AdjustedClose
+		a.AdjustedClose = 0
+		adjusted = append(adjusted, &a)
+	}
+	return adjusted
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"stockticker/internal/stockclient"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustedPrices(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Adjusted closes replace raw closes", func(t *testing.T) {
+		cacheClient := NewMockCacheClient()
+		stockCtrler, err := NewStockController(&mockStockClient{}, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		prices, err := stockCtrler.DailyPrices(ctx, "msft", PriceOptions{Adjusted: true})
+		require.NoError(t, err)
+		require.True(t, prices.Adjusted)
+		require.Equal(t, []*stockclient.DayData{
+			{Date: adjustedDailyData[0].Date, Close: 90.35, Dividend: 0.5},
+			{Date: adjustedDailyData[1].Date, Close: 93.9, SplitCoefficient: 2},
+		}, prices.DailyData)
+		// Cached daily data isn't modified
+		require.Equal(t, 188.8, adjustedDailyData[1].Close)
+		require.Contains(t, cacheClient.Cache, "symbol_adjusted:MSFT")
+	})
+
+	t.Run("Averages use adjusted closes", func(t *testing.T) {
+		stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+
+		viewData, err := stockCtrler.Stock(ctx, PriceOptions{Adjusted: true}, nil)
+		require.NoError(t, err)
+		require.Equal(t, true, viewData["adjusted"])
+		require.InDelta(t, (90.35+93.9)/2, viewData["avgClose"], 0.0001)
+	})
+
+	t.Run("Adjusted closes can be converted", func(t *testing.T) {
+		stockClient := &mockStockClient{FXRates: map[string][]*stockclient.FXRate{
+			"USD:EUR": {{Date: adjustedDailyData[1].Date, Rate: 0.5}},
+		}}
+		stockCtrler, err := NewStockController(stockClient, NewMockCacheClient(), "MSFT", 2, WithFXProvider(stockClient))
+		require.NoError(t, err)
+
+		prices, err := stockCtrler.DailyPrices(ctx, "MSFT", PriceOptions{Currency: "EUR", Adjusted: true})
+		require.NoError(t, err)
+		require.InDelta(t, 45.175, prices.DailyData[0].Close, 0.0001)
+		require.InDelta(t, 0.25, prices.DailyData[0].Dividend, 0.0001)
+		require.Equal(t, float64(2), prices.DailyData[1].SplitCoefficient)
+	})
+
+	t.Run("Only equities can be adjusted", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		stockCtrler, err := NewStockController(stockClient, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+
+		_, err = stockCtrler.DailyPrices(ctx, "crypto:BTC-USD", PriceOptions{Adjusted: true})
+		require.ErrorIs(t, err, stockclient.ErrUnsupportedAssetClass)
+		require.Zero(t, stockClient.Calls)
+	})
+}
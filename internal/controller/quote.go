@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"stockticker/internal/stockclient"
+	"time"
+)
+
+const (
+	// Quotes are intraday data so are only cached briefly
+	defaultQuoteTTL = time.Minute
+)
+
+// Quote returns the latest quote for symbol, returning ErrInvalidSymbol if the symbol is malformed
+func (sc *StockController) Quote(ctx context.Context, symbol string) (*stockclient.Quote, error) {
+	symbol, err := quoteSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	return cachedFetch(ctx, sc, fmt.Sprintf("quote:%s", symbol), "quote", sc.settings.Load().ttls.Quote, func() (*stockclient.Quote, error) {
+		return sc.client.Quote(ctx, symbol)
+	})
+}
+
+// quoteSymbol normalises symbol, returning stockclient.ErrUnsupportedAssetClass if quotes aren't available for it
+func quoteSymbol(symbol string) (string, error) {
+	symbol, err := normaliseSymbol(symbol)
+	if err != nil {
+		return "", err
+	}
+	if class, _ := stockclient.ParseSymbol(symbol); class != stockclient.Equity {
+		return "", fmt.Errorf("quotes are %w '%s'", stockclient.ErrUnsupportedAssetClass, class)
+	}
+	return symbol, nil
+}
@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"stockticker/internal/logging"
+	"stockticker/internal/stockclient"
+	"sync"
+	"time"
+)
+
+const (
+	// Number of recent updates kept so reconnecting subscribers can catch up on what they missed
+	quoteHistorySize = 256
+)
+
+var (
+	ErrSubscriptionClosed = errors.New("subscription closed")
+	ErrTooManySymbols     = errors.New("too many symbols")
+)
+
+// QuoteUpdate is a quote for a subscribed symbol. IDs increase with every update published by a poller
+type QuoteUpdate struct {
+	ID     uint64
+	Symbol string
+	Quote  *stockclient.Quote
+}
+
+// QuotePoller periodically fetches quotes for every symbol with at least one subscriber and fans the results out to
+// all interested subscribers, so the number of upstream requests is independent of the number of subscribers
+type QuotePoller struct {
+	stockCtrler *StockController
+	interval    time.Duration
+	// Zero means unlimited
+	maxSymbolsPerSub int
+	wake             chan struct{}
+
+	mu        sync.Mutex
+	lastID    uint64
+	subs      map[*Subscription]struct{}
+	refCounts map[string]int
+	latest    map[string]QuoteUpdate
+	history   []QuoteUpdate
+}
+
+func NewQuotePoller(stockCtrler *StockController, interval time.Duration, maxSymbolsPerSub int) (*QuotePoller, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("poll interval must be greater than zero")
+	}
+
+	return &QuotePoller{
+		stockCtrler:      stockCtrler,
+		interval:         interval,
+		maxSymbolsPerSub: maxSymbolsPerSub,
+		wake:             make(chan struct{}, 1),
+		subs:             make(map[*Subscription]struct{}),
+		refCounts:        make(map[string]int),
+		latest:           make(map[string]QuoteUpdate),
+	}, nil
+}
+
+// Run polls until ctx is cancelled
+func (p *QuotePoller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.closeAll()
+			return
+		case <-ticker.C:
+			p.poll(ctx, p.symbols())
+		case <-p.wake:
+			p.poll(ctx, p.unpolledSymbols())
+		}
+	}
+}
+
+// Subscribe registers interest in symbols on behalf of the request ctx belongs to. If lastID is non-zero, updates published after lastID that are still held
+// in memory are replayed, otherwise the most recent quote for each symbol is sent.
+// bufSize is the number of updates that can be queued before the subscription is considered too slow and closed, so
+// should be at least the maximum number of symbols per subscription
+func (p *QuotePoller) Subscribe(ctx context.Context, symbols []string, lastID uint64, bufSize int) (*Subscription, error) {
+	sub := &Subscription{
+		ctx:     ctx,
+		poller:  p,
+		updates: make(chan QuoteUpdate, bufSize),
+		symbols: make(map[string]struct{}),
+	}
+	for _, symbol := range symbols {
+		if err := sub.Add(symbol); err != nil {
+			sub.Close()
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subs[sub] = struct{}{}
+	for _, update := range p.replay(sub, lastID) {
+		p.send(sub, update)
+	}
+	return sub, nil
+}
+
+// replay returns the updates a subscriber that last saw lastID has missed. Must be called with p.mu held
+func (p *QuotePoller) replay(sub *Subscription, lastID uint64) []QuoteUpdate {
+	updates := []QuoteUpdate{}
+	// IDs from before a restart or from another replica can't be trusted, nor can anything older than the history
+	canResume := lastID != 0 && lastID <= p.lastID && len(p.history) > 0 && p.history[0].ID <= lastID+1
+	if canResume {
+		for _, update := range p.history {
+			if update.ID > lastID && sub.has(update.Symbol) {
+				updates = append(updates, update)
+			}
+		}
+		if len(updates) <= cap(sub.updates) {
+			return updates
+		}
+		updates = updates[:0]
+	}
+
+	for _, symbol := range sub.Symbols() {
+		if update, ok := p.latest[symbol]; ok {
+			updates = append(updates, update)
+		}
+	}
+	slices.SortFunc(updates, func(a, b QuoteUpdate) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return updates
+}
+
+// send queues update for sub, closing sub if its buffer is full. Must be called with p.mu held
+func (p *QuotePoller) send(sub *Subscription, update QuoteUpdate) {
+	select {
+	case sub.updates <- update:
+	default:
+		logging.FromContext(sub.ctx).Warnf("Closing quote subscription that is not keeping up with updates")
+		p.remove(sub)
+	}
+}
+
+// remove unregisters sub and closes its updates channel. Must be called with p.mu held
+func (p *QuotePoller) remove(sub *Subscription) {
+	if _, ok := p.subs[sub]; !ok {
+		return
+	}
+	delete(p.subs, sub)
+	close(sub.updates)
+}
+
+func (p *QuotePoller) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for sub := range p.subs {
+		p.remove(sub)
+	}
+}
+
+// addSymbol registers sub's interest in symbol, sending it the latest quote if one is known and sub is already
+// registered. New subscriptions receive the latest quotes as part of their replay instead. It reports whether symbol has
+// been polled. Must be called with p.mu held
+func (p *QuotePoller) addSymbol(sub *Subscription, symbol string) bool {
+	p.refCounts[symbol]++
+	update, polled := p.latest[symbol]
+	if _, registered := p.subs[sub]; polled && registered {
+		p.send(sub, update)
+	}
+	return polled
+}
+
+func (p *QuotePoller) removeSymbol(symbol string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refCounts[symbol]--
+	if p.refCounts[symbol] <= 0 {
+		delete(p.refCounts, symbol)
+		delete(p.latest, symbol)
+	}
+}
+
+func (p *QuotePoller) symbols() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	symbols := make([]string, 0, len(p.refCounts))
+	for symbol := range p.refCounts {
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// unpolledSymbols returns subscribed symbols that don't have a quote yet
+func (p *QuotePoller) unpolledSymbols() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	symbols := []string{}
+	for symbol := range p.refCounts {
+		if _, ok := p.latest[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+func (p *QuotePoller) poll(ctx context.Context, symbols []string) {
+	for _, symbol := range symbols {
+		quote, err := p.stockCtrler.Quote(ctx, symbol)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("Failed to poll quote for %s: %v", symbol, err)
+			continue
+		}
+		p.publish(symbol, quote)
+	}
+}
+
+// publish sends quote to all subscribers of symbol if it differs from the last quote sent
+func (p *QuotePoller) publish(symbol string, quote *stockclient.Quote) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, subscribed := p.refCounts[symbol]; !subscribed {
+		return
+	}
+	if prev, ok := p.latest[symbol]; ok && sameQuote(prev.Quote, quote) {
+		return
+	}
+
+	p.lastID++
+	update := QuoteUpdate{ID: p.lastID, Symbol: symbol, Quote: quote}
+	p.latest[symbol] = update
+	p.history = append(p.history, update)
+	if len(p.history) > quoteHistorySize {
+		p.history = p.history[len(p.history)-quoteHistorySize:]
+	}
+
+	for sub := range p.subs {
+		if sub.has(symbol) {
+			p.send(sub, update)
+		}
+	}
+}
+
+func sameQuote(a, b *stockclient.Quote) bool {
+	return a.Price == b.Price && a.Change == b.Change && a.Volume == b.Volume && a.Timestamp.Equal(b.Timestamp)
+}
+
+// Subscription receives quote updates for a changeable set of symbols
+type Subscription struct {
+	// The context of the request that subscribed. Only used for logging
+	ctx     context.Context
+	poller  *QuotePoller
+	updates chan QuoteUpdate
+
+	mu      sync.Mutex
+	symbols map[string]struct{}
+	closed  bool
+}
+
+// Updates returns the channel updates are delivered on. It is closed if the subscription is closed, including when the
+// subscriber falls too far behind
+func (s *Subscription) Updates() <-chan QuoteUpdate {
+	return s.updates
+}
+
+// Add subscribes to symbol, returning ErrInvalidSymbol if it's malformed, stockclient.ErrUnsupportedAssetClass if it
+// doesn't have quotes or ErrTooManySymbols if the subscription is at its limit
+func (s *Subscription) Add(symbol string) error {
+	symbol, err := quoteSymbol(symbol)
+	if err != nil {
+		return err
+	}
+
+	// The poller's lock is held throughout, as it is when publishing, so a quote published while symbol is being added
+	// isn't sent as well as the latest quote
+	p := s.poller
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSubscriptionClosed
+	}
+	if _, ok := s.symbols[symbol]; ok {
+		s.mu.Unlock()
+		return nil
+	}
+	if p.maxSymbolsPerSub > 0 && len(s.symbols) >= p.maxSymbolsPerSub {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: at most %d symbols can be subscribed to", ErrTooManySymbols, p.maxSymbolsPerSub)
+	}
+	s.symbols[symbol] = struct{}{}
+	s.mu.Unlock()
+
+	if !p.addSymbol(s, symbol) {
+		select {
+		case p.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Remove unsubscribes from symbol
+func (s *Subscription) Remove(symbol string) {
+	symbol, err := normaliseSymbol(symbol)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	_, ok := s.symbols[symbol]
+	delete(s.symbols, symbol)
+	s.mu.Unlock()
+
+	if ok {
+		s.poller.removeSymbol(symbol)
+	}
+}
+
+// Symbols returns the subscribed symbols in alphabetical order
+func (s *Subscription) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	symbols := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		symbols = append(symbols, symbol)
+	}
+	slices.Sort(symbols)
+	return symbols
+}
+
+// Close unsubscribes from all symbols. It's safe to call more than once
+func (s *Subscription) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	symbols := s.symbols
+	s.symbols = make(map[string]struct{})
+	s.mu.Unlock()
+
+	for symbol := range symbols {
+		s.poller.removeSymbol(symbol)
+	}
+
+	s.poller.mu.Lock()
+	s.poller.remove(s)
+	s.poller.mu.Unlock()
+}
+
+func (s *Subscription) has(symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.symbols[symbol]
+	return ok
+}
@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"context"
+	"stockticker/internal/cache"
+	"stockticker/internal/stockclient"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQuotePoller(t *testing.T, stockClient *mockStockClient, maxSymbolsPerSub int) *QuotePoller {
+	cacheClient, _ := cache.NewNullClient("", 0)
+	stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+	require.NoError(t, err)
+	poller, err := NewQuotePoller(stockCtrler, time.Hour, maxSymbolsPerSub)
+	require.NoError(t, err)
+	return poller
+}
+
+func quoteWithPrice(symbol string, price float64) *stockclient.Quote {
+	q := *quote
+	q.Symbol = symbol
+	q.Price = price
+	return &q
+}
+
+func TestQuotePoller(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Updates are only published when a quote changes", func(t *testing.T) {
+		stockClient := &mockStockClient{Quotes: map[string]*stockclient.Quote{"MSFT": quoteWithPrice("MSFT", 100)}}
+		poller := newTestQuotePoller(t, stockClient, 0)
+
+		sub, err := poller.Subscribe(ctx, []string{"msft"}, 0, 10)
+		require.NoError(t, err)
+		defer sub.Close()
+		require.Equal(t, []string{"MSFT"}, sub.Symbols())
+
+		poller.poll(ctx, poller.symbols())
+		update := <-sub.Updates()
+		require.Equal(t, uint64(1), update.ID)
+		require.Equal(t, "MSFT", update.Symbol)
+		require.Equal(t, 100.0, update.Quote.Price)
+
+		poller.poll(ctx, poller.symbols())
+		require.Empty(t, sub.Updates())
+
+		stockClient.Quotes["MSFT"] = quoteWithPrice("MSFT", 101)
+		poller.poll(ctx, poller.symbols())
+		update = <-sub.Updates()
+		require.Equal(t, uint64(2), update.ID)
+		require.Equal(t, 101.0, update.Quote.Price)
+	})
+
+	t.Run("Subscribers to the same symbol share upstream requests", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		poller := newTestQuotePoller(t, stockClient, 0)
+
+		subs := []*Subscription{}
+		for range 5 {
+			sub, err := poller.Subscribe(ctx, []string{"MSFT"}, 0, 10)
+			require.NoError(t, err)
+			defer sub.Close()
+			subs = append(subs, sub)
+		}
+
+		poller.poll(ctx, poller.symbols())
+		require.Equal(t, 1, stockClient.Calls)
+		for _, sub := range subs {
+			update := <-sub.Updates()
+			require.Equal(t, uint64(1), update.ID)
+		}
+	})
+
+	t.Run("Only subscribed symbols are delivered", func(t *testing.T) {
+		stockClient := &mockStockClient{Quotes: map[string]*stockclient.Quote{
+			"MSFT": quoteWithPrice("MSFT", 100),
+			"AAPL": quoteWithPrice("AAPL", 200),
+		}}
+		poller := newTestQuotePoller(t, stockClient, 0)
+
+		msftSub, err := poller.Subscribe(ctx, []string{"MSFT"}, 0, 10)
+		require.NoError(t, err)
+		defer msftSub.Close()
+		bothSub, err := poller.Subscribe(ctx, []string{"MSFT", "AAPL"}, 0, 10)
+		require.NoError(t, err)
+		defer bothSub.Close()
+
+		poller.poll(ctx, []string{"AAPL", "MSFT"})
+		require.Len(t, msftSub.Updates(), 1)
+		require.Len(t, bothSub.Updates(), 2)
+
+		bothSub.Remove("AAPL")
+		require.Equal(t, []string{"MSFT"}, bothSub.Symbols())
+		require.ElementsMatch(t, []string{"MSFT"}, poller.symbols())
+	})
+
+	t.Run("Symbols added to a subscription receive the latest quote", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		poller := newTestQuotePoller(t, stockClient, 0)
+
+		existing, err := poller.Subscribe(ctx, []string{"MSFT"}, 0, 10)
+		require.NoError(t, err)
+		defer existing.Close()
+		poller.poll(ctx, poller.symbols())
+
+		sub, err := poller.Subscribe(ctx, nil, 0, 10)
+		require.NoError(t, err)
+		defer sub.Close()
+		require.Empty(t, sub.Updates())
+
+		require.NoError(t, sub.Add("MSFT"))
+		update := <-sub.Updates()
+		require.Equal(t, uint64(1), update.ID)
+		require.Equal(t, 1, stockClient.Calls)
+	})
+
+	t.Run("Symbols added while a quote is published receive it once", func(t *testing.T) {
+		poller := newTestQuotePoller(t, &mockStockClient{}, 0)
+		// Keeps MSFT subscribed so its latest quote is kept
+		holder, err := poller.Subscribe(ctx, []string{"MSFT"}, 0, 1)
+		require.NoError(t, err)
+		defer holder.Close()
+		sub, err := poller.Subscribe(ctx, nil, 0, 10)
+		require.NoError(t, err)
+		defer sub.Close()
+		poller.publish("MSFT", quoteWithPrice("MSFT", 0))
+
+		for i := range 200 {
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				poller.publish("MSFT", quoteWithPrice("MSFT", float64(i+1)))
+			}()
+			require.NoError(t, sub.Add("MSFT"))
+			wg.Wait()
+
+			seen := map[uint64]bool{}
+			for len(sub.Updates()) > 0 {
+				update := <-sub.Updates()
+				require.False(t, seen[update.ID], "update %d was sent twice", update.ID)
+				seen[update.ID] = true
+			}
+			sub.Remove("MSFT")
+		}
+	})
+
+	t.Run("Reconnecting subscribers receive missed updates", func(t *testing.T) {
+		stockClient := &mockStockClient{Quotes: map[string]*stockclient.Quote{}}
+		poller := newTestQuotePoller(t, stockClient, 0)
+
+		keepAlive, err := poller.Subscribe(ctx, []string{"MSFT"}, 0, 10)
+		require.NoError(t, err)
+		defer keepAlive.Close()
+
+		for i := range 3 {
+			stockClient.Quotes["MSFT"] = quoteWithPrice("MSFT", float64(100+i))
+			poller.poll(ctx, poller.symbols())
+		}
+
+		sub, err := poller.Subscribe(ctx, []string{"MSFT"}, 1, 10)
+		require.NoError(t, err)
+		defer sub.Close()
+		require.Equal(t, uint64(2), (<-sub.Updates()).ID)
+		require.Equal(t, uint64(3), (<-sub.Updates()).ID)
+		require.Empty(t, sub.Updates())
+
+		// Unknown IDs result in the latest quote only
+		sub, err = poller.Subscribe(ctx, []string{"MSFT"}, 1000, 10)
+		require.NoError(t, err)
+		defer sub.Close()
+		require.Equal(t, uint64(3), (<-sub.Updates()).ID)
+		require.Empty(t, sub.Updates())
+	})
+
+	t.Run("Slow subscribers are closed", func(t *testing.T) {
+		stockClient := &mockStockClient{Quotes: map[string]*stockclient.Quote{}}
+		poller := newTestQuotePoller(t, stockClient, 0)
+
+		sub, err := poller.Subscribe(ctx, []string{"MSFT"}, 0, 1)
+		require.NoError(t, err)
+		defer sub.Close()
+
+		for i := range 2 {
+			stockClient.Quotes["MSFT"] = quoteWithPrice("MSFT", float64(100+i))
+			poller.poll(ctx, poller.symbols())
+		}
+
+		<-sub.Updates()
+		_, ok := <-sub.Updates()
+		require.False(t, ok)
+	})
+
+	t.Run("Subscriptions are limited to a maximum number of symbols", func(t *testing.T) {
+		poller := newTestQuotePoller(t, &mockStockClient{}, 2)
+
+		_, err := poller.Subscribe(ctx, []string{"MSFT", "AAPL", "NVDA"}, 0, 10)
+		require.ErrorIs(t, err, ErrTooManySymbols)
+		require.Empty(t, poller.symbols())
+
+		sub, err := poller.Subscribe(ctx, []string{"MSFT", "AAPL"}, 0, 10)
+		require.NoError(t, err)
+		defer sub.Close()
+		require.ErrorIs(t, sub.Add("NVDA"), ErrTooManySymbols)
+		require.ErrorIs(t, sub.Add("NV DA"), ErrInvalidSymbol)
+		require.ErrorIs(t, sub.Add("crypto:BTC-USD"), stockclient.ErrUnsupportedAssetClass)
+	})
+
+	t.Run("Closing a subscription releases its symbols", func(t *testing.T) {
+		poller := newTestQuotePoller(t, &mockStockClient{}, 0)
+
+		sub, err := poller.Subscribe(ctx, []string{"MSFT"}, 0, 10)
+		require.NoError(t, err)
+		sub.Close()
+		sub.Close()
+		require.Empty(t, poller.symbols())
+		_, ok := <-sub.Updates()
+		require.False(t, ok)
+		require.ErrorIs(t, sub.Add("MSFT"), ErrSubscriptionClosed)
+	})
+}
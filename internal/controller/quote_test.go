@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"stockticker/internal/cache"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	t.Run("Quote is normalised and cached", func(t *testing.T) {
+		ctx := context.Background()
+		stockClient := &mockStockClient{}
+		cacheClient := NewMockCacheClient()
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		q, err := stockCtrler.Quote(ctx, "msft")
+		require.NoError(t, err)
+		require.Equal(t, "MSFT", stockClient.QuoteSymbol)
+		require.Equal(t, quote, q)
+		require.Contains(t, cacheClient.Cache, "quote:MSFT")
+
+		q, err = stockCtrler.Quote(ctx, "MSFT")
+		require.NoError(t, err)
+		require.Equal(t, 1, stockClient.Calls)
+		require.Equal(t, quote, q)
+	})
+
+	t.Run("Quote with invalid symbols", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		for _, symbol := range []string{"", "MS FT", "MSFT&apikey=x", "../MSFT"} {
+			_, err = stockCtrler.Quote(context.Background(), symbol)
+			require.ErrorIs(t, err, ErrInvalidSymbol)
+		}
+		require.Equal(t, 0, stockClient.Calls)
+	})
+
+	t.Run("Quote is included in stock view data", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		viewData, err := stockCtrler.Stock(context.Background(), PriceOptions{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "MSFT", stockClient.QuoteSymbol)
+		require.Equal(t, quote, viewData["quote"])
+	})
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"stockticker/internal/chart"
+	"stockticker/internal/logging"
+)
+
+const (
+	maxSparklineDays   = 365
+	minSparklineSize   = 8
+	maxSparklineWidth  = 1000
+	maxSparklineHeight = 500
+)
+
+var ErrInvalidSparkline = errors.New("invalid sparkline")
+
+// Sparkline is a rendered sparkline image
+type Sparkline struct {
+	PNG []byte `json:"png"`
+	// Changes whenever the image does
+	ETag string `json:"etag"`
+}
+
+// Sparkline renders the last days closes of symbol as a width by height PNG. Images are cached alongside the daily
+// data they're rendered from
+func (sc *StockController) Sparkline(ctx context.Context, symbol string, days, width, height int) (*Sparkline, error) {
+	symbol, err := normaliseSymbol(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if days < 2 || days > maxSparklineDays {
+		return nil, fmt.Errorf("%w: days must be between 2 and %d", ErrInvalidSparkline, maxSparklineDays)
+	}
+	if width < minSparklineSize || width > maxSparklineWidth || height < minSparklineSize || height > maxSparklineHeight {
+		return nil, fmt.Errorf("%w: size must be between %dx%d and %dx%d", ErrInvalidSparkline, minSparklineSize, minSparklineSize, maxSparklineWidth, maxSparklineHeight)
+	}
+
+	key := fmt.Sprintf("sparkline:%s:%d:%dx%d", symbol, days, width, height)
+	cacheCtx, cancel := context.WithTimeout(ctx, sc.cacheTimeout)
+	defer cancel()
+	cached, cacheErr := cachedJSON[Sparkline](cacheCtx, sc.cache, key)
+	if cacheErr != nil {
+		logging.FromContext(ctx).Warnf("Failed to get sparkline from cache: %v", cacheErr)
+	}
+	if cached != nil {
+		return cached, nil
+	}
+
+	stock, err := sc.dailyStock(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if len(stock.DailyData) == 0 {
+		return nil, fmt.Errorf("no data returned for %s", symbol)
+	}
+
+	img, err := chart.SparklinePNG(stock.DailyData, days, width, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render sparkline: %w", err)
+	}
+	hash := sha256.Sum256(img)
+	sparkline := &Sparkline{
+		PNG:  img,
+		ETag: fmt.Sprintf(`"%s"`, hex.EncodeToString(hash[:8])),
+	}
+
+	if cacheErr == nil {
+		// Expire with the daily data so the image is redrawn when there's a new close
+		if err := cacheJSON(cacheCtx, sc.cache, key, sparkline, cacheTTL()); err != nil {
+			logging.FromContext(ctx).Warnf("Failed to cache sparkline: %v", err)
+		}
+	}
+	return sparkline, nil
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSparkline(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Rendered images are cached by parameters", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient := NewMockCacheClient()
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		sparkline, err := stockCtrler.Sparkline(ctx, "msft", 30, 120, 30)
+		require.NoError(t, err)
+		require.NotEmpty(t, sparkline.ETag)
+		img, err := png.Decode(bytes.NewReader(sparkline.PNG))
+		require.NoError(t, err)
+		require.Equal(t, 120, img.Bounds().Dx())
+		require.Equal(t, 30, img.Bounds().Dy())
+		require.Contains(t, cacheClient.Cache, "sparkline:MSFT:30:120x30")
+
+		cached, err := stockCtrler.Sparkline(ctx, "MSFT", 30, 120, 30)
+		require.NoError(t, err)
+		require.Equal(t, sparkline, cached)
+		require.Equal(t, 1, stockClient.Calls)
+
+		// Other sizes are rendered from the cached daily data
+		resized, err := stockCtrler.Sparkline(ctx, "MSFT", 30, 60, 20)
+		require.NoError(t, err)
+		require.NotEqual(t, sparkline.ETag, resized.ETag)
+		require.Equal(t, 1, stockClient.Calls)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+
+		for name, params := range map[string][3]int{
+			"too few days":  {1, 120, 30},
+			"too many days": {366, 120, 30},
+			"too narrow":    {30, 7, 30},
+			"too tall":      {30, 120, 501},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := stockCtrler.Sparkline(ctx, "MSFT", params[0], params[1], params[2])
+				require.ErrorIs(t, err, ErrInvalidSparkline)
+			})
+		}
+
+		_, err = stockCtrler.Sparkline(ctx, "MS FT", 30, 120, 30)
+		require.ErrorIs(t, err, ErrInvalidSymbol)
+	})
+}
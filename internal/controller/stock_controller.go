@@ -2,124 +2,334 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
 	"stockticker/internal/cache"
+	"stockticker/internal/chart"
+	"stockticker/internal/logging"
 	"stockticker/internal/stockclient"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
-	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
 )
 
 const (
-	// TODO: May want separate timeouts for reading and writing. Probably also want to make this a command line switch or env var
-	CACHE_TIMEOUT = 15
+	// TODO: May want separate timeouts for reading and writing
+	defaultCacheTimeout = 15 * time.Second
+)
+
+var (
+	ErrInvalidSymbol = errors.New("invalid symbol")
+
+	// Covers exchange suffixes (e.g. TSCO.LON), share classes (e.g. BRK-B) and indices (e.g. ^GSPC)
+	symbolRegexp = regexp.MustCompile(`^[A-Z0-9.\-^=]{1,32}$`)
+	// Crypto and FX pairs, e.g. BTC-USD
+	pairRegexp = regexp.MustCompile(`^[A-Z0-9]{1,10}-[A-Z0-9]{1,10}$`)
 )
 
 type StockController struct {
-	client  stockclient.Client
-	numDays int
+	client   stockclient.Client
+	settings atomic.Pointer[stockSettings]
+	cache    cache.Client
+	// Bounds each cache read and write
+	cacheTimeout time.Duration
+	// Limits requests to the stock client. nil means unlimited
+	limiter *rate.Limiter
+	// Converts prices to other currencies. nil means conversion is disabled
+	fx stockclient.FXProvider
+	// The symbols in configured watchlists. Only they and the main page's symbol are labelled in the newest data metric,
+	// so the number of series doesn't grow with the symbols users look up
+	watched atomic.Pointer[map[string]struct{}]
+}
+
+type StockControllerOption func(*StockController)
+
+// CacheTTLs are how long responses are cached for, apart from daily data which is cached until the next day
+type CacheTTLs struct {
+	Quote        time.Duration
+	SymbolSearch time.Duration
+	Overview     time.Duration
+}
+
+var DefaultCacheTTLs = CacheTTLs{
+	Quote:        defaultQuoteTTL,
+	SymbolSearch: defaultSymbolSearchTTL,
+	Overview:     defaultOverviewTTL,
+}
+
+// stockSettings can be replaced while running. They're swapped as a whole so each request sees a consistent set
+type stockSettings struct {
 	symbol  string
-	cache   cache.Client
+	numDays int
+	ttls    CacheTTLs
+}
+
+// SymbolSummary is a snapshot of a symbol's most recent closing prices
+type SymbolSummary struct {
+	Symbol        string    `json:"symbol"`
+	Date          time.Time `json:"date"`
+	LastClose     float64   `json:"lastClose"`
+	Change        float64   `json:"change"`
+	ChangePercent float64   `json:"changePercent"`
+	DaysReturned  int       `json:"daysReturned"`
+	AvgClose      float64   `json:"avgClose"`
+}
+
+// WithCacheTimeout replaces the default bound on each cache read and write. It applies to every controller built on
+// the stock controller
+func WithCacheTimeout(timeout time.Duration) StockControllerOption {
+	return func(sc *StockController) {
+		sc.cacheTimeout = timeout
+	}
+}
+
+// WithCacheTTLs replaces the default cache TTLs
+func WithCacheTTLs(ttls CacheTTLs) StockControllerOption {
+	return func(sc *StockController) {
+		settings := *sc.settings.Load()
+		settings.ttls = ttls
+		sc.settings.Store(&settings)
+	}
 }
 
-func NewStockController(client stockclient.Client, cache cache.Client, symbol string, numDays int) (*StockController, error) {
-	return &StockController{
-		client:  client,
+// WithUpstreamRateLimit limits the number of requests made by the stock client to requestsPerMinute
+func WithUpstreamRateLimit(requestsPerMinute int) StockControllerOption {
+	return func(sc *StockController) {
+		if requestsPerMinute > 0 {
+			sc.limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(requestsPerMinute)), 1)
+		}
+	}
+}
+
+func NewStockController(client stockclient.Client, cache cache.Client, symbol string, numDays int, opts ...StockControllerOption) (*StockController, error) {
+	sc := &StockController{
+		client:       client,
+		cache:        cache,
+		cacheTimeout: defaultCacheTimeout,
+	}
+	sc.settings.Store(&stockSettings{
+		symbol:  symbol,
 		numDays: numDays,
+		ttls:    DefaultCacheTTLs,
+	})
+	for _, opt := range opts {
+		opt(sc)
+	}
+	return sc, nil
+}
+
+// Reconfigure replaces the symbol shown on the main page, the number of days returned and the cache TTLs. Requests
+// already in progress continue with the previous settings
+func (sc *StockController) Reconfigure(symbol string, numDays int, ttls CacheTTLs) {
+	old := sc.settings.Swap(&stockSettings{
 		symbol:  symbol,
-		cache:   cache,
-	}, nil
+		numDays: numDays,
+		ttls:    ttls,
+	})
+	if !sc.tracked(old.symbol) {
+		newestDataTimestamp.DeleteLabelValues(old.symbol)
+	}
 }
 
-func (sc *StockController) Stock(ctx context.Context) (map[string]any, error) {
-	var err error
-	var stock *stockclient.Stock
+// setWatched replaces the symbols in configured watchlists, which are reported in the newest data metric
+func (sc *StockController) setWatched(symbols []string) {
+	watched := make(map[string]struct{}, len(symbols))
+	for _, symbol := range symbols {
+		watched[symbol] = struct{}{}
+	}
+	old := sc.watched.Swap(&watched)
+	if old == nil {
+		return
+	}
+	for symbol := range *old {
+		if !sc.tracked(symbol) {
+			newestDataTimestamp.DeleteLabelValues(symbol)
+		}
+	}
+}
 
-	cacheCtx, cancel := context.WithTimeout(ctx, CACHE_TIMEOUT*time.Second)
-	defer cancel()
-	stock, cacheErr := sc.cachedStock(cacheCtx)
-	if cacheErr != nil {
-		log.Warnf("Failed to get stock from cache: %v", cacheErr)
+// tracked reports whether symbol is shown on the main page or is in a configured watchlist
+func (sc *StockController) tracked(symbol string) bool {
+	if symbol == sc.settings.Load().symbol {
+		return true
+	}
+	if watched := sc.watched.Load(); watched != nil {
+		_, ok := (*watched)[symbol]
+		return ok
 	}
+	return false
+}
 
-	if stock == nil {
-		log.Debug("Response not cached")
-		timer := prometheus.NewTimer(stockClientTimer.WithLabelValues("daily"))
-		// TODO: Distributed rate limiting might be useful here depending on how the third-party implement rate limiting
-		stock, err = sc.client.Stock(sc.symbol, stockclient.Ascending)
-		timer.ObserveDuration()
+// Stock returns view data for the configured symbol with closes presented according to opts and a chart including
+// movingAverages, each given as a number of days
+func (sc *StockController) Stock(ctx context.Context, opts PriceOptions, movingAverages []int) (_ map[string]any, err error) {
+	ctx, span := tracer.Start(ctx, "StockController.Stock")
+	defer endSpan(span, &err)
+
+	if err := chart.ValidateMovingAverages(movingAverages); err != nil {
+		return nil, err
+	}
+
+	settings := sc.settings.Load()
+	span.SetAttributes(attribute.String("symbol", settings.symbol))
+	// Earlier days are fetched so moving averages cover the whole chart
+	prices, err := sc.prices(ctx, settings.symbol, opts, chart.Lookback(movingAverages))
+	if err != nil {
+		return nil, err
+	}
+
+	numDays := min(settings.numDays, len(prices.DailyData))
+	dailyData := prices.DailyData[:numDays]
+	logging.FromContext(ctx).Debugf("numDays: %d", numDays)
+	viewData := map[string]any{
+		"daysReq":        settings.numDays,
+		"daysRet":        numDays,
+		"dailyData":      dailyData,
+		"avgClose":       sc.avgClosePrice(dailyData),
+		"adjusted":       prices.Adjusted,
+		"chart":          chart.PriceSVG(prices.DailyData, numDays, movingAverages),
+		"movingAverages": joinInts(movingAverages),
+	}
+	if prices.Converted {
+		viewData["currency"] = prices.Currency
+		viewData["originalCurrency"] = prices.OriginalCurrency
+	}
+
+	// The quote and overview are supplementary so the page is still useful without them. Only equities have them
+	if class, _ := stockclient.ParseSymbol(settings.symbol); class == stockclient.Equity {
+		quote, err := sc.Quote(ctx, settings.symbol)
 		if err != nil {
-			stockClientErrors.WithLabelValues("daily").Inc()
-			return nil, err
+			logging.FromContext(ctx).Warnf("Failed to get quote: %v", err)
+		} else {
+			viewData["quote"] = quote
 		}
 
-		// TODO: Is there a way to detect if the provider is lagged and cache for less time?
-		if cacheErr == nil {
-			ttl := cacheTTL()
-			log.Debugf("Caching response with TTL: %v", ttl)
-			cacheErr = sc.cacheStock(cacheCtx, stock, ttl)
-			if cacheErr != nil {
-				log.Warnf("Failed to cache stock: %v", cacheErr)
-			}
+		overview, err := sc.Overview(ctx, settings.symbol)
+		if err != nil {
+			logging.FromContext(ctx).Warnf("Failed to get overview: %v", err)
+		} else {
+			viewData["overview"] = overview
+			viewData["marketCap"] = humaniseAmount(overview.MarketCap)
 		}
-	} else {
-		log.Debug("Response cached")
 	}
 
-	numDays := min(sc.numDays, len(stock.DailyData))
-	log.Debugf("numDays: %d", numDays)
-	nDaysOfDailyData := stock.DailyData[:numDays]
-	viewData := map[string]any{
-		"daysReq":   sc.numDays,
-		"daysRet":   numDays,
-		"dailyData": nDaysOfDailyData,
-		"avgClose":  sc.avgClosePrice(nDaysOfDailyData),
-	}
 	return viewData, nil
 }
 
-// cachedStock attempts to get stock data from cache
-func (sc *StockController) cachedStock(ctx context.Context) (*stockclient.Stock, error) {
-	timer := prometheus.NewTimer(stockCacheTimer.WithLabelValues("read"))
-	defer timer.ObserveDuration()
-
-	stockStr, err := sc.cache.Get(ctx, fmt.Sprintf("symbol:%s", sc.symbol))
+// Summary returns the latest close, the change from the previous close and the average close over the configured
+// number of days for symbol
+func (sc *StockController) Summary(ctx context.Context, symbol string) (*SymbolSummary, error) {
+	symbol, err := normaliseSymbol(symbol)
 	if err != nil {
-		stockCacheErrors.WithLabelValues("read").Inc()
 		return nil, err
 	}
-	if stockStr == "" {
-		return nil, nil
-	}
 
-	var stock stockclient.Stock
-	err = json.Unmarshal([]byte(stockStr), &stock)
+	stock, err := sc.dailyStock(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
-	return &stock, nil
+	if len(stock.DailyData) == 0 {
+		return nil, fmt.Errorf("no data returned for %s", symbol)
+	}
+
+	numDays := min(sc.settings.Load().numDays, len(stock.DailyData))
+	nDaysOfDailyData := stock.DailyData[:numDays]
+	summary := &SymbolSummary{
+		Symbol:       symbol,
+		Date:         nDaysOfDailyData[0].Date,
+		LastClose:    nDaysOfDailyData[0].Close,
+		DaysReturned: numDays,
+		AvgClose:     sc.avgClosePrice(nDaysOfDailyData),
+	}
+	if len(stock.DailyData) > 1 {
+		prevClose := stock.DailyData[1].Close
+		summary.Change = summary.LastClose - prevClose
+		if prevClose != 0 {
+			summary.ChangePercent = summary.Change / prevClose * 100
+		}
+	}
+	return summary, nil
 }
 
-// cacheStock caches the provided stock data
-func (sc *StockController) cacheStock(ctx context.Context, stock *stockclient.Stock, ttl time.Duration) error {
-	timer := prometheus.NewTimer(stockCacheTimer.WithLabelValues("write"))
-	defer timer.ObserveDuration()
+// Summaries summarises each of symbols, fetching up to maxConcurrency at once subject to the upstream rate limit.
+// The summary for a symbol is nil if its error is set
+func (sc *StockController) Summaries(ctx context.Context, symbols []string, maxConcurrency int) ([]*SymbolSummary, []error) {
+	summaries := make([]*SymbolSummary, len(symbols))
+	errs := make([]error, len(symbols))
+	forEachConcurrently(symbols, maxConcurrency, func(i int, symbol string) {
+		summaries[i], errs[i] = sc.Summary(ctx, symbol)
+	})
+	return summaries, errs
+}
 
-	data, err := json.Marshal(stock)
-	if err != nil {
-		return err
+// forEachConcurrently calls fn for each symbol with at most maxConcurrency calls running at once
+func forEachConcurrently(symbols []string, maxConcurrency int, fn func(i int, symbol string)) {
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			fn(i, symbol)
+		}()
 	}
-	// TODO: Worth compressing before caching?
-	err = sc.cache.Set(ctx, fmt.Sprintf("symbol:%s", sc.symbol), string(data), ttl)
+	wg.Wait()
+}
+
+// dailyStock returns the daily data for symbol, from cache where possible
+func (sc *StockController) dailyStock(ctx context.Context, symbol string) (*stockclient.Stock, error) {
+	// TODO: Is there a way to detect if the provider is lagged and cache for less time?
+	stock, err := cachedFetch(ctx, sc, fmt.Sprintf("symbol:%s", symbol), "daily", cacheTTL(), func() (*stockclient.Stock, error) {
+		return sc.client.Stock(ctx, symbol, stockclient.Ascending)
+	})
 	if err != nil {
-		stockCacheErrors.WithLabelValues("write").Inc()
-		return err
+		return nil, err
+	}
+	sc.recordNewestData(symbol, stock)
+	return stock, nil
+}
+
+// recordNewestData reports the date of the newest data point served for symbol if it's tracked, which shows if the
+// provider is lagging or old data is cached
+func (sc *StockController) recordNewestData(symbol string, stock *stockclient.Stock) {
+	if !sc.tracked(symbol) {
+		return
 	}
+	var newest time.Time
+	for _, dayData := range stock.DailyData {
+		if dayData.Date.After(newest) {
+			newest = dayData.Date
+		}
+	}
+	if !newest.IsZero() {
+		newestDataTimestamp.WithLabelValues(symbol).Set(float64(newest.Unix()))
+	}
+}
 
-	return nil
+// normaliseSymbol upper cases symbol and lower cases its asset class prefix, returning ErrInvalidSymbol if it contains
+// unexpected characters
+func normaliseSymbol(symbol string) (string, error) {
+	class, rest := stockclient.ParseSymbol(strings.TrimSpace(symbol))
+	rest = strings.ToUpper(rest)
+	switch class {
+	case stockclient.Equity:
+		if symbolRegexp.MatchString(rest) {
+			return rest, nil
+		}
+	case stockclient.Crypto, stockclient.FXPair:
+		if pairRegexp.MatchString(rest) {
+			return fmt.Sprintf("%s:%s", class, rest), nil
+		}
+	}
+	return "", fmt.Errorf("%w: '%s'", ErrInvalidSymbol, strings.TrimSpace(symbol))
 }
 
 func cacheTTL() time.Duration {
@@ -129,6 +339,15 @@ func cacheTTL() time.Duration {
 	return time.Until(midnightTomorrow)
 }
 
+// joinInts formats ints as a comma separated list
+func joinInts(ints []int) string {
+	strs := make([]string, 0, len(ints))
+	for _, i := range ints {
+		strs = append(strs, strconv.Itoa(i))
+	}
+	return strings.Join(strs, ",")
+}
+
 func (sc *StockController) avgClosePrice(dailyData []*stockclient.DayData) float64 {
 	avgClose := float64(0)
 	for i := range dailyData {
@@ -7,12 +7,17 @@ import (
 	"io"
 	"os"
 	"stockticker/internal/cache"
+	"stockticker/internal/chart"
 	"stockticker/internal/stockclient"
+	"sync"
 	"testing"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -29,6 +34,63 @@ var (
 		},
 	}
 
+	symbolMatches = []*stockclient.SymbolMatch{
+		{
+			Symbol:     "TSCO.LON",
+			Name:       "Tesco PLC",
+			Type:       "Equity",
+			Region:     "United Kingdom",
+			Currency:   "GBX",
+			MatchScore: 0.7273,
+		},
+		{
+			Symbol:     "TSCDY",
+			Name:       "Tesco PLC",
+			Type:       "Equity",
+			Region:     "United States",
+			Currency:   "USD",
+			MatchScore: 0.7143,
+		},
+	}
+
+	quote = &stockclient.Quote{
+		Symbol:        "MSFT",
+		Price:         418.16,
+		PreviousClose: 416.72,
+		Change:        1.44,
+		ChangePercent: 0.3456,
+		Volume:        17145300,
+		Timestamp:     time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC),
+	}
+
+	adjustedDailyData = []*stockclient.DayData{
+		{
+			Date:          time.Date(2019, 9, 20, 0, 0, 0, 0, time.UTC),
+			Close:         90.35,
+			AdjustedClose: 90.35,
+			Dividend:      0.5,
+		},
+		{
+			Date:             time.Date(2019, 9, 19, 0, 0, 0, 0, time.UTC),
+			Close:            188.8,
+			AdjustedClose:    93.9,
+			SplitCoefficient: 2,
+		},
+	}
+
+	overview = &stockclient.Overview{
+		Symbol:     "MSFT",
+		Name:       "Microsoft Corporation",
+		Exchange:   "NASDAQ",
+		Currency:   "USD",
+		Sector:     "TECHNOLOGY",
+		Industry:   "SERVICES-PREPACKAGED SOFTWARE",
+		MarketCap:  3108224205000,
+		PERatio:    35.43,
+		WeekHigh52: 467.56,
+		WeekLow52:  323.2,
+	}
+
 	cachedDailyData = []*stockclient.DayData{
 		{
 			Date:  time.Date(2020, 10, 3, 0, 0, 0, 0, time.UTC),
@@ -47,34 +109,123 @@ var (
 
 // Mock stock client
 type mockStockClient struct {
-	Symbol string
+	mu             sync.Mutex
+	Symbol         string
+	Keywords       string
+	QuoteSymbol    string
+	OverviewSymbol string
+	// Quotes overrides the quote returned per symbol
+	Quotes map[string]*stockclient.Quote
+	// Stocks overrides the daily data returned per symbol
+	Stocks map[string][]*stockclient.DayData
+	// StockErrs causes Stock to fail for the given symbols
+	StockErrs map[string]error
+	// FXRates are the rates returned by FXDaily keyed by FROM:TO
+	FXRates map[string][]*stockclient.FXRate
+	FXCalls int
+	Calls   int
 }
 
-func (sc *mockStockClient) Stock(symbol string, sortOrder stockclient.Order) (*stockclient.Stock, error) {
+func (sc *mockStockClient) Stock(ctx context.Context, symbol string, sortOrder stockclient.Order) (*stockclient.Stock, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
 	sc.Symbol = symbol
+	sc.Calls++
+	if err, ok := sc.StockErrs[symbol]; ok {
+		return nil, err
+	}
+	if d, ok := sc.Stocks[symbol]; ok {
+		return &stockclient.Stock{DailyData: d}, nil
+	}
 	stock := &stockclient.Stock{DailyData: dailyData}
 	return stock, nil
 }
 
+func (sc *mockStockClient) AdjustedStock(ctx context.Context, symbol string, sortOrder stockclient.Order) (*stockclient.Stock, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.Symbol = symbol
+	sc.Calls++
+	return &stockclient.Stock{DailyData: adjustedDailyData}, nil
+}
+
+func (sc *mockStockClient) Overview(ctx context.Context, symbol string) (*stockclient.Overview, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.OverviewSymbol = symbol
+	sc.Calls++
+	return overview, nil
+}
+
+func (sc *mockStockClient) SearchSymbols(ctx context.Context, keywords string) ([]*stockclient.SymbolMatch, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.Keywords = keywords
+	sc.Calls++
+	return symbolMatches, nil
+}
+
+func (sc *mockStockClient) Quote(ctx context.Context, symbol string) (*stockclient.Quote, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.QuoteSymbol = symbol
+	sc.Calls++
+	if q, ok := sc.Quotes[symbol]; ok {
+		return q, nil
+	}
+	return quote, nil
+}
+
+func (sc *mockStockClient) FXDaily(ctx context.Context, fromCurrency, toCurrency string) ([]*stockclient.FXRate, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.FXCalls++
+	rates, ok := sc.FXRates[fromCurrency+":"+toCurrency]
+	if !ok {
+		return nil, fmt.Errorf("no rates for %s to %s", fromCurrency, toCurrency)
+	}
+	return rates, nil
+}
+
 // Mock cache
 type mockCacheClient struct {
-	GetKey string
-	Cache  map[string]string
+	mu      sync.Mutex
+	GetKeys []string
+	Cache   map[string]string
+	TTLs    map[string]time.Duration
 }
 
 func NewMockCacheClient() *mockCacheClient {
 	return &mockCacheClient{
 		Cache: make(map[string]string),
+		TTLs:  make(map[string]time.Duration),
 	}
 }
 
 func (c *mockCacheClient) Get(ctx context.Context, key string) (string, error) {
-	c.GetKey = key
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.GetKeys = append(c.GetKeys, key)
 	return c.Cache[key], nil
 }
 
 func (c *mockCacheClient) Set(ctx context.Context, key, val string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Cache[key] = val
+	c.TTLs[key] = ttl
+	return nil
+}
+
+func (c *mockCacheClient) Update(ctx context.Context, key string, ttl time.Duration, fn func(val string) (string, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	val, err := fn(c.Cache[key])
+	if err != nil {
+		return err
+	}
 	c.Cache[key] = val
+	c.TTLs[key] = ttl
 	return nil
 }
 
@@ -94,6 +245,11 @@ func (c *mockFailingCacheClient) Set(ctx context.Context, key, val string, ttl t
 	return fmt.Errorf("Set failure")
 }
 
+func (c *mockFailingCacheClient) Update(ctx context.Context, key string, ttl time.Duration, fn func(val string) (string, error)) error {
+	c.Key = key
+	return fmt.Errorf("Update failure")
+}
+
 func (c *mockFailingCacheClient) Close() {}
 
 func assertViewData(t *testing.T, viewData map[string]any, numDaysReq int, expAvgClose float64) {
@@ -124,6 +280,12 @@ func assertCachedViewData(t *testing.T, viewData map[string]any) {
 	require.Equal(t, 190.46666666666666666666666666667, viewData["avgClose"])
 }
 
+func histogramCount(t *testing.T, observer prometheus.Observer) uint64 {
+	metric := &dto.Metric{}
+	require.NoError(t, observer.(prometheus.Metric).Write(metric))
+	return metric.Histogram.GetSampleCount()
+}
+
 func TestMain(m *testing.M) {
 	log.SetOutput(io.Discard)
 	code := m.Run()
@@ -153,7 +315,7 @@ func TestStock(t *testing.T) {
 			require.NoError(t, err)
 
 			ctx := context.Background()
-			viewData, err := stockCtrler.Stock(ctx)
+			viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, nil)
 			require.Equal(t, test.symbol, stockClient.Symbol)
 			require.NoError(t, err)
 			assertViewData(t, viewData, test.numDays, test.expAvgClose)
@@ -171,14 +333,17 @@ func TestStock(t *testing.T) {
 		stockStr, _ := cacheClient.Get(ctx, "symbol:NVDA")
 		require.Empty(t, stockStr)
 
-		viewData, err := stockCtrler.Stock(ctx)
+		start := time.Now()
+		viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, nil)
 		require.NoError(t, err)
+		require.GreaterOrEqual(t, testutil.ToFloat64(lastFetchSuccess.WithLabelValues("daily")), float64(start.Unix()))
 
 		stockStr, _ = cacheClient.Get(ctx, "symbol:NVDA")
-		var stock stockclient.Stock
-		err = json.Unmarshal([]byte(stockStr), &stock)
+		var cached cachedValue[stockclient.Stock]
+		err = json.Unmarshal([]byte(stockStr), &cached)
 		require.NoError(t, err)
-		require.ElementsMatch(t, stock.DailyData, dailyData)
+		require.WithinDuration(t, time.Now(), cached.CachedAt, time.Minute)
+		require.ElementsMatch(t, cached.Value.DailyData, dailyData)
 
 		assertViewData(t, viewData, 2, 92.375)
 	})
@@ -188,8 +353,9 @@ func TestStock(t *testing.T) {
 		stockClient := &mockStockClient{}
 		cacheClient := NewMockCacheClient()
 
-		data, err := json.Marshal(&stockclient.Stock{
-			DailyData: cachedDailyData,
+		data, err := json.Marshal(&cachedValue[*stockclient.Stock]{
+			CachedAt: time.Now().Add(-time.Hour),
+			Value:    &stockclient.Stock{DailyData: cachedDailyData},
 		})
 		require.NoError(t, err)
 		err = cacheClient.Set(ctx, "symbol:NVDA", string(data), 100*time.Hour)
@@ -198,10 +364,47 @@ func TestStock(t *testing.T) {
 		stockCtrler, err := NewStockController(stockClient, cacheClient, "NVDA", 3)
 		require.NoError(t, err)
 
-		viewData, err := stockCtrler.Stock(ctx)
-		require.Equal(t, "symbol:NVDA", cacheClient.GetKey)
+		cacheReads := histogramCount(t, stockCacheEntryAge.WithLabelValues("daily"))
+		viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, nil)
+		require.Contains(t, cacheClient.GetKeys, "symbol:NVDA")
 		require.NoError(t, err)
 		assertCachedViewData(t, viewData)
+		require.Empty(t, stockClient.Symbol)
+		require.Equal(t, cacheReads+1, histogramCount(t, stockCacheEntryAge.WithLabelValues("daily")))
+		newest := time.Date(2020, 10, 3, 0, 0, 0, 0, time.UTC)
+		require.Equal(t, float64(newest.Unix()), testutil.ToFloat64(newestDataTimestamp.WithLabelValues("NVDA")))
+	})
+
+	t.Run("Stock with a result for NVDA cached in an earlier format", func(t *testing.T) {
+		ctx := context.Background()
+		stockClient := &mockStockClient{}
+		cacheClient := NewMockCacheClient()
+
+		// Cached without the time, and in a form that can't be decoded
+		for _, cached := range []string{`{"dailyData": []}`, `[]`} {
+			require.NoError(t, cacheClient.Set(ctx, "symbol:NVDA", cached, 100*time.Hour))
+			stockCtrler, err := NewStockController(stockClient, cacheClient, "NVDA", 2)
+			require.NoError(t, err)
+
+			viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, nil)
+			require.NoError(t, err)
+			require.Equal(t, "NVDA", stockClient.Symbol)
+			assertViewData(t, viewData, 2, 92.375)
+			require.Contains(t, cacheClient.Cache["symbol:NVDA"], `"cachedAt"`)
+		}
+	})
+
+	t.Run("Stock for a crypto pair without a quote or overview", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		stockCtrler, err := NewStockController(stockClient, NewMockCacheClient(), "crypto:BTC-USD", 2)
+		require.NoError(t, err)
+
+		viewData, err := stockCtrler.Stock(context.Background(), PriceOptions{}, nil)
+		require.NoError(t, err)
+		require.Empty(t, stockClient.QuoteSymbol)
+		require.Empty(t, stockClient.OverviewSymbol)
+		require.NotContains(t, viewData, "quote")
+		require.NotContains(t, viewData, "overview")
 	})
 
 	t.Run("Stock with failing caching for AAPL and 2 days", func(t *testing.T) {
@@ -211,9 +414,151 @@ func TestStock(t *testing.T) {
 		require.NoError(t, err)
 
 		ctx := context.Background()
-		viewData, err := stockCtrler.Stock(ctx)
+		viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, nil)
 		require.Equal(t, "AAPL", stockClient.Symbol)
 		require.NoError(t, err)
 		assertViewData(t, viewData, 2, 92.375)
 	})
 }
+
+func TestSummary(t *testing.T) {
+	t.Run("Summary of the configured number of days", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 1)
+		require.NoError(t, err)
+
+		summary, err := stockCtrler.Summary(context.Background(), "aapl")
+		require.NoError(t, err)
+		require.Equal(t, "AAPL", stockClient.Symbol)
+		require.Equal(t, "AAPL", summary.Symbol)
+		require.Equal(t, dailyData[0].Date, summary.Date)
+		require.Equal(t, 90.35, summary.LastClose)
+		require.InDelta(t, -4.05, summary.Change, 1e-9)
+		require.InDelta(t, -4.2902542, summary.ChangePercent, 1e-6)
+		require.Equal(t, 1, summary.DaysReturned)
+		require.Equal(t, 90.35, summary.AvgClose)
+	})
+
+	t.Run("Summary with an invalid symbol", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 1)
+		require.NoError(t, err)
+
+		_, err = stockCtrler.Summary(context.Background(), "AA PL")
+		require.ErrorIs(t, err, ErrInvalidSymbol)
+	})
+}
+
+func TestReconfigure(t *testing.T) {
+	ctx := context.Background()
+	stockClient := &mockStockClient{}
+	cacheClient := NewMockCacheClient()
+	stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 1)
+	require.NoError(t, err)
+
+	stockCtrler.Reconfigure("NVDA", 2, CacheTTLs{Quote: time.Second, SymbolSearch: time.Hour, Overview: time.Hour})
+	viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "NVDA", stockClient.Symbol)
+	assertViewData(t, viewData, 2, 92.375)
+
+	_, err = stockCtrler.Quote(ctx, "MSFT")
+	require.NoError(t, err)
+	require.Equal(t, time.Second, cacheClient.TTLs["quote:MSFT"])
+}
+
+// newestDataSymbols returns the symbols with a newest data series
+func newestDataSymbols(t *testing.T) []string {
+	ch := make(chan prometheus.Metric, 100)
+	newestDataTimestamp.Collect(ch)
+	close(ch)
+	symbols := []string{}
+	for m := range ch {
+		metric := &dto.Metric{}
+		require.NoError(t, m.Write(metric))
+		symbols = append(symbols, metric.GetLabel()[0].GetValue())
+	}
+	return symbols
+}
+
+func TestNewestDataSymbols(t *testing.T) {
+	ctx := context.Background()
+	stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MAINPAGE", 1)
+	require.NoError(t, err)
+	watchlistCtrler, err := NewWatchlistController(stockCtrler, NewMockCacheClient(), map[string][]string{"tracked": {"watched"}}, 2)
+	require.NoError(t, err)
+
+	for _, symbol := range []string{"MAINPAGE", "WATCHED", "LOOKEDUP"} {
+		_, err := stockCtrler.dailyStock(ctx, symbol)
+		require.NoError(t, err)
+	}
+	require.Subset(t, newestDataSymbols(t), []string{"MAINPAGE", "WATCHED"})
+	require.NotContains(t, newestDataSymbols(t), "LOOKEDUP")
+
+	// Series are removed once their symbol is no longer tracked
+	require.NoError(t, watchlistCtrler.SetConfigured(map[string][]string{"tracked": {"OTHER"}}))
+	require.NotContains(t, newestDataSymbols(t), "WATCHED")
+	stockCtrler.Reconfigure("OTHER", 1, DefaultCacheTTLs)
+	require.NotContains(t, newestDataSymbols(t), "MAINPAGE")
+}
+
+func TestStockChart(t *testing.T) {
+	ctx := context.Background()
+	stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 1)
+	require.NoError(t, err)
+
+	t.Run("Moving averages use days before the window", func(t *testing.T) {
+		viewData, err := stockCtrler.Stock(ctx, PriceOptions{}, []int{2})
+		require.NoError(t, err)
+		require.Equal(t, 1, viewData["daysRet"])
+		require.Len(t, viewData["dailyData"], 1)
+		require.Equal(t, 90.35, viewData["avgClose"])
+		require.Equal(t, "2", viewData["movingAverages"])
+		require.Contains(t, viewData["chart"], "2 day average")
+	})
+
+	t.Run("Invalid moving averages", func(t *testing.T) {
+		_, err := stockCtrler.Stock(ctx, PriceOptions{}, []int{1})
+		require.ErrorIs(t, err, chart.ErrInvalidMovingAverage)
+	})
+}
+
+func TestNormaliseSymbol(t *testing.T) {
+	valid := map[string]string{
+		" msft ":         "MSFT",
+		"tsco.lon":       "TSCO.LON",
+		"^GSPC":          "^GSPC",
+		"crypto:btc-usd": "crypto:BTC-USD",
+		"CRYPTO:ETH-EUR": "crypto:ETH-EUR",
+		"fx:eur-usd":     "fx:EUR-USD",
+	}
+	for symbol, expected := range valid {
+		normalised, err := normaliseSymbol(symbol)
+		require.NoError(t, err, symbol)
+		require.Equal(t, expected, normalised)
+	}
+
+	for _, symbol := range []string{"", "AA PL", "crypto:BTC", "fx:EUR-", "fx:EUR.USD", "bond:US10Y", "crypto:"} {
+		_, err := normaliseSymbol(symbol)
+		require.ErrorIs(t, err, ErrInvalidSymbol, symbol)
+	}
+}
+
+func TestUpstreamRateLimit(t *testing.T) {
+	stockClient := &mockStockClient{}
+	cacheClient, _ := cache.NewNullClient("", 0)
+	stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2, WithUpstreamRateLimit(1))
+	require.NoError(t, err)
+
+	_, err = stockCtrler.Summary(context.Background(), "MSFT")
+	require.NoError(t, err)
+
+	// The next request isn't allowed for a minute
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = stockCtrler.Summary(ctx, "MSFT")
+	require.Error(t, err)
+	require.Equal(t, 1, stockClient.Calls)
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"stockticker/internal/cache"
+	"stockticker/internal/logging"
+	"sync"
+	"time"
+)
+
+// editableStore holds named values created via the API. They're persisted in the cache, without an expiry, so all
+// replicas agree and kept in memory for when the cache is disabled or unavailable
+type editableStore[T any] struct {
+	cache   cache.Client
+	key     string
+	timeout time.Duration
+
+	mu    sync.Mutex
+	local map[string]T
+}
+
+func newEditableStore[T any](cache cache.Client, key string, timeout time.Duration) *editableStore[T] {
+	return &editableStore[T]{
+		cache:   cache,
+		key:     key,
+		timeout: timeout,
+		local:   make(map[string]T),
+	}
+}
+
+// all returns a copy of the stored values, preferring the cached copy
+func (s *editableStore[T]) all(ctx context.Context) map[string]T {
+	cacheCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	cached, err := cachedJSON[map[string]T](cacheCtx, s.cache, s.key)
+	if err != nil {
+		logging.FromContext(ctx).Warnf("Failed to get %s from cache: %v", s.key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached != nil && *cached != nil {
+		s.local = *cached
+	}
+	return maps.Clone(s.local)
+}
+
+// update applies fn to the stored values and persists the result. The cached copy is updated atomically so concurrent
+// updates from other replicas aren't lost
+func (s *editableStore[T]) update(ctx context.Context, fn func(map[string]T) error) error {
+	var vals map[string]T
+	var fnErr error
+	cacheCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	err := updateCachedJSON(cacheCtx, s.cache, s.key, 0, func(cached *map[string]T) (map[string]T, error) {
+		s.mu.Lock()
+		if cached != nil && *cached != nil {
+			vals = maps.Clone(*cached)
+		} else {
+			vals = maps.Clone(s.local)
+		}
+		s.mu.Unlock()
+		fnErr = fn(vals)
+		return vals, fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to persist %s: %w", s.key, err)
+	}
+
+	s.mu.Lock()
+	s.local = vals
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"stockticker/internal/cache"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditableStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Concurrent updates from different replicas aren't lost", func(t *testing.T) {
+		cacheClient := NewMockCacheClient()
+		replicas := []*editableStore[int]{
+			newEditableStore[int](cacheClient, "values", time.Second),
+			newEditableStore[int](cacheClient, "values", time.Second),
+		}
+
+		var wg sync.WaitGroup
+		for i := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := replicas[i%2].update(ctx, func(vals map[string]int) error {
+					vals[fmt.Sprint(i)] = i
+					return nil
+				})
+				require.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+
+		require.Len(t, replicas[0].all(ctx), 20)
+		require.Equal(t, replicas[0].all(ctx), replicas[1].all(ctx))
+	})
+
+	t.Run("Values are kept in memory when the cache is disabled", func(t *testing.T) {
+		cacheClient, _ := cache.NewNullClient("", 0)
+		store := newEditableStore[int](cacheClient, "values", time.Second)
+		require.NoError(t, store.update(ctx, func(vals map[string]int) error {
+			vals["a"] = 1
+			return nil
+		}))
+		require.NoError(t, store.update(ctx, func(vals map[string]int) error {
+			vals["b"] = 2
+			return nil
+		}))
+		require.Equal(t, map[string]int{"a": 1, "b": 2}, store.all(ctx))
+	})
+
+	t.Run("Errors from the update are returned unchanged", func(t *testing.T) {
+		store := newEditableStore[int](NewMockCacheClient(), "values", time.Second)
+		err := store.update(ctx, func(vals map[string]int) error {
+			vals["a"] = 1
+			return ErrWatchlistNotFound
+		})
+		require.ErrorIs(t, err, ErrWatchlistNotFound)
+		require.Empty(t, store.all(ctx))
+	})
+
+	t.Run("Cache failures are returned", func(t *testing.T) {
+		store := newEditableStore[int](&mockFailingCacheClient{}, "values", time.Second)
+		err := store.update(ctx, func(vals map[string]int) error {
+			vals["a"] = 1
+			return nil
+		})
+		require.ErrorContains(t, err, "failed to persist values")
+	})
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stockticker/internal/stockclient"
+	"strings"
+	"time"
+)
+
+const (
+	// Listings change rarely so searches can be cached for much longer than price data
+	defaultSymbolSearchTTL = 24 * time.Hour
+	// Single characters match too much to be useful, so aren't worth a request
+	minSymbolSearchQueryLen = 2
+	maxSymbolSearchQueryLen = 64
+)
+
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+type SymbolSearchResult struct {
+	Query string `json:"query"`
+	// Valid is true if one of the matches is exactly the query, i.e. the query is a known symbol
+	Valid   bool                       `json:"valid"`
+	Matches []*stockclient.SymbolMatch `json:"matches"`
+}
+
+// SearchSymbols looks up symbols matching query, returning ErrInvalidSearchQuery if the query is unusable
+func (sc *StockController) SearchSymbols(ctx context.Context, query string) (*SymbolSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if len(query) < minSymbolSearchQueryLen || len(query) > maxSymbolSearchQueryLen {
+		return nil, fmt.Errorf("%w: must be between %d and %d characters", ErrInvalidSearchQuery, minSymbolSearchQueryLen, maxSymbolSearchQueryLen)
+	}
+
+	cacheKey := fmt.Sprintf("symbol_search:%s", strings.ToLower(query))
+	matches, err := cachedFetch(ctx, sc, cacheKey, "symbol_search", sc.settings.Load().ttls.SymbolSearch, func() ([]*stockclient.SymbolMatch, error) {
+		matches, err := sc.client.SearchSymbols(ctx, query)
+		if matches == nil {
+			matches = []*stockclient.SymbolMatch{}
+		}
+		return matches, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SymbolSearchResult{
+		Query:   query,
+		Matches: matches,
+	}
+	for _, m := range result.Matches {
+		if strings.EqualFold(m.Symbol, query) {
+			result.Valid = true
+			break
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"stockticker/internal/cache"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchSymbols(t *testing.T) {
+	t.Run("Search for an exact symbol", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		result, err := stockCtrler.SearchSymbols(context.Background(), " tscdy ")
+		require.NoError(t, err)
+		require.Equal(t, "tscdy", stockClient.Keywords)
+		require.Equal(t, "tscdy", result.Query)
+		require.True(t, result.Valid)
+		require.ElementsMatch(t, symbolMatches, result.Matches)
+	})
+
+	t.Run("Search for a partial name", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		result, err := stockCtrler.SearchSymbols(context.Background(), "tesco")
+		require.NoError(t, err)
+		require.False(t, result.Valid)
+		require.Len(t, result.Matches, 2)
+	})
+
+	t.Run("Search results are cached", func(t *testing.T) {
+		ctx := context.Background()
+		stockClient := &mockStockClient{}
+		cacheClient := NewMockCacheClient()
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		_, err = stockCtrler.SearchSymbols(ctx, "Tesco")
+		require.NoError(t, err)
+		require.Contains(t, cacheClient.Cache, "symbol_search:tesco")
+
+		result, err := stockCtrler.SearchSymbols(ctx, "TESCO")
+		require.NoError(t, err)
+		require.Equal(t, 1, stockClient.Calls)
+		require.ElementsMatch(t, symbolMatches, result.Matches)
+	})
+
+	t.Run("Search with invalid queries", func(t *testing.T) {
+		stockClient := &mockStockClient{}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+
+		for _, query := range []string{"", "   ", "M", strings.Repeat("A", maxSymbolSearchQueryLen+1)} {
+			_, err = stockCtrler.SearchSymbols(context.Background(), query)
+			require.ErrorIs(t, err, ErrInvalidSearchQuery)
+		}
+		require.Equal(t, 0, stockClient.Calls)
+	})
+}
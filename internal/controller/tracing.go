@@ -0,0 +1,19 @@
+package controller
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("stockticker/internal/controller")
+
+// endSpan ends span, marking it as failed if *err is set. It's deferred with a pointer to the function's named error
+// result so it sees the error that's returned
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
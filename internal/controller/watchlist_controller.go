@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"stockticker/internal/cache"
+	"stockticker/internal/logging"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	watchlistsCacheKey  = "watchlists"
+	maxWatchlistSymbols = 50
+)
+
+var (
+	ErrWatchlistNotFound = errors.New("watchlist not found")
+	ErrWatchlistReadOnly = errors.New("watchlist is defined in config and can't be changed")
+	ErrInvalidWatchlist  = errors.New("invalid watchlist")
+
+	watchlistNameRegexp = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+)
+
+type Watchlist struct {
+	Name    string   `json:"name"`
+	Symbols []string `json:"symbols"`
+	// Watchlists defined in config can't be changed via the API
+	ReadOnly bool `json:"readOnly"`
+}
+
+// WatchlistRow summarises a single symbol in a watchlist. Summary is nil if the symbol's data couldn't be retrieved
+type WatchlistRow struct {
+	Symbol  string         `json:"symbol"`
+	Summary *SymbolSummary `json:"summary,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+type WatchlistController struct {
+	stockCtrler *StockController
+	// Replaced as a whole on reload so it's never modified in place
+	configured atomic.Pointer[map[string][]string]
+	editable   *editableStore[[]string]
+	// Maximum number of symbols fetched at once when summarising a watchlist
+	maxConcurrency int
+}
+
+func NewWatchlistController(stockCtrler *StockController, cache cache.Client, configured map[string][]string, maxConcurrency int) (*WatchlistController, error) {
+	if maxConcurrency <= 0 {
+		return nil, fmt.Errorf("max concurrency must be greater than zero")
+	}
+
+	wc := &WatchlistController{
+		stockCtrler:    stockCtrler,
+		editable:       newEditableStore[[]string](cache, watchlistsCacheKey, stockCtrler.cacheTimeout),
+		maxConcurrency: maxConcurrency,
+	}
+	if err := wc.SetConfigured(configured); err != nil {
+		return nil, err
+	}
+	return wc, nil
+}
+
+// SetConfigured replaces the read-only watchlists defined in config. Nothing is changed if any are invalid
+func (wc *WatchlistController) SetConfigured(configured map[string][]string) error {
+	watchlists := make(map[string][]string)
+	for name, symbols := range configured {
+		watchlist, err := newWatchlist(name, symbols)
+		if err != nil {
+			return err
+		}
+		watchlists[watchlist.Name] = watchlist.Symbols
+	}
+	wc.configured.Store(&watchlists)
+
+	symbols := []string{}
+	for _, watchlist := range watchlists {
+		symbols = append(symbols, watchlist...)
+	}
+	wc.stockCtrler.setWatched(symbols)
+	return nil
+}
+
+// newWatchlist validates and normalises name and symbols
+func newWatchlist(name string, symbols []string) (*Watchlist, error) {
+	if !watchlistNameRegexp.MatchString(name) {
+		return nil, fmt.Errorf("%w: name '%s' must be 1-32 lower case letters, digits, underscores or hyphens", ErrInvalidWatchlist, name)
+	}
+	if len(symbols) == 0 || len(symbols) > maxWatchlistSymbols {
+		return nil, fmt.Errorf("%w: '%s' must have between 1 and %d symbols", ErrInvalidWatchlist, name, maxWatchlistSymbols)
+	}
+
+	watchlist := &Watchlist{Name: name, Symbols: []string{}}
+	for _, symbol := range symbols {
+		symbol, err := normaliseSymbol(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidWatchlist, err)
+		}
+		if !slices.Contains(watchlist.Symbols, symbol) {
+			watchlist.Symbols = append(watchlist.Symbols, symbol)
+		}
+	}
+	return watchlist, nil
+}
+
+// Watchlists returns all watchlists ordered by name
+func (wc *WatchlistController) Watchlists(ctx context.Context) []*Watchlist {
+	configured := *wc.configured.Load()
+	watchlists := []*Watchlist{}
+	for name, symbols := range wc.editable.all(ctx) {
+		if _, ok := configured[name]; !ok {
+			watchlists = append(watchlists, &Watchlist{Name: name, Symbols: symbols})
+		}
+	}
+	for name, symbols := range configured {
+		watchlists = append(watchlists, &Watchlist{Name: name, Symbols: symbols, ReadOnly: true})
+	}
+	slices.SortFunc(watchlists, func(a, b *Watchlist) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return watchlists
+}
+
+func (wc *WatchlistController) Watchlist(ctx context.Context, name string) (*Watchlist, error) {
+	if symbols, ok := (*wc.configured.Load())[name]; ok {
+		return &Watchlist{Name: name, Symbols: symbols, ReadOnly: true}, nil
+	}
+	if symbols, ok := wc.editable.all(ctx)[name]; ok {
+		return &Watchlist{Name: name, Symbols: symbols}, nil
+	}
+	return nil, ErrWatchlistNotFound
+}
+
+// SaveWatchlist creates or replaces the watchlist called name
+func (wc *WatchlistController) SaveWatchlist(ctx context.Context, name string, symbols []string) (*Watchlist, error) {
+	if _, ok := (*wc.configured.Load())[name]; ok {
+		return nil, ErrWatchlistReadOnly
+	}
+	watchlist, err := newWatchlist(name, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	err = wc.editable.update(ctx, func(watchlists map[string][]string) error {
+		watchlists[watchlist.Name] = watchlist.Symbols
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+func (wc *WatchlistController) DeleteWatchlist(ctx context.Context, name string) error {
+	if _, ok := (*wc.configured.Load())[name]; ok {
+		return ErrWatchlistReadOnly
+	}
+
+	return wc.editable.update(ctx, func(watchlists map[string][]string) error {
+		if _, ok := watchlists[name]; !ok {
+			return ErrWatchlistNotFound
+		}
+		delete(watchlists, name)
+		return nil
+	})
+}
+
+// Summary returns view data containing a summary row for each symbol in the watchlist. Symbols are fetched
+// concurrently, subject to the stock controller's upstream rate limit
+func (wc *WatchlistController) Summary(ctx context.Context, name string) (map[string]any, error) {
+	watchlist, err := wc.Watchlist(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]*WatchlistRow, len(watchlist.Symbols))
+	summaries, errs := wc.stockCtrler.Summaries(ctx, watchlist.Symbols, wc.maxConcurrency)
+	for i, symbol := range watchlist.Symbols {
+		rows[i] = &WatchlistRow{Symbol: symbol, Summary: summaries[i]}
+		if errs[i] != nil {
+			logging.FromContext(ctx).Warnf("Failed to summarise %s for watchlist %s: %v", symbol, name, errs[i])
+			rows[i].Error = "failed to retrieve stock data"
+		}
+	}
+
+	viewData := map[string]any{
+		"watchlist": watchlist,
+		"daysReq":   wc.stockCtrler.settings.Load().numDays,
+		"rows":      rows,
+	}
+	return viewData, nil
+}
+
+func (wc *WatchlistController) ViewTemplate() string {
+	return "watchlist_view.tmpl"
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"stockticker/internal/cache"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchlistController(t *testing.T) {
+	ctx := context.Background()
+	configured := map[string][]string{"morning": {"msft", "AAPL", "MSFT"}}
+
+	newWatchlistCtrler := func(t *testing.T, stockClient *mockStockClient, cacheClient cache.Client) *WatchlistController {
+		stockCtrler, err := NewStockController(stockClient, cacheClient, "MSFT", 2)
+		require.NoError(t, err)
+		watchlistCtrler, err := NewWatchlistController(stockCtrler, cacheClient, configured, 2)
+		require.NoError(t, err)
+		return watchlistCtrler
+	}
+
+	t.Run("Configured watchlists are normalised and read-only", func(t *testing.T) {
+		watchlistCtrler := newWatchlistCtrler(t, &mockStockClient{}, NewMockCacheClient())
+
+		watchlist, err := watchlistCtrler.Watchlist(ctx, "morning")
+		require.NoError(t, err)
+		require.Equal(t, &Watchlist{Name: "morning", Symbols: []string{"MSFT", "AAPL"}, ReadOnly: true}, watchlist)
+
+		_, err = watchlistCtrler.SaveWatchlist(ctx, "morning", []string{"NVDA"})
+		require.ErrorIs(t, err, ErrWatchlistReadOnly)
+		require.ErrorIs(t, watchlistCtrler.DeleteWatchlist(ctx, "morning"), ErrWatchlistReadOnly)
+	})
+
+	t.Run("Invalid configured watchlists", func(t *testing.T) {
+		stockCtrler, err := NewStockController(&mockStockClient{}, NewMockCacheClient(), "MSFT", 2)
+		require.NoError(t, err)
+
+		for _, watchlists := range []map[string][]string{
+			{"Morning": {"MSFT"}},
+			{"morning": {}},
+			{"morning": {"MS FT"}},
+		} {
+			_, err = NewWatchlistController(stockCtrler, NewMockCacheClient(), watchlists, 2)
+			require.ErrorIs(t, err, ErrInvalidWatchlist)
+		}
+	})
+
+	t.Run("Configured watchlists can be replaced", func(t *testing.T) {
+		watchlistCtrler := newWatchlistCtrler(t, &mockStockClient{}, NewMockCacheClient())
+
+		require.NoError(t, watchlistCtrler.SetConfigured(map[string][]string{"evening": {"nvda"}}))
+		_, err := watchlistCtrler.Watchlist(ctx, "morning")
+		require.ErrorIs(t, err, ErrWatchlistNotFound)
+		watchlist, err := watchlistCtrler.Watchlist(ctx, "evening")
+		require.NoError(t, err)
+		require.Equal(t, []string{"NVDA"}, watchlist.Symbols)
+
+		// Nothing changes if any are invalid
+		err = watchlistCtrler.SetConfigured(map[string][]string{"morning": {"MSFT"}, "Evening": {"NVDA"}})
+		require.ErrorIs(t, err, ErrInvalidWatchlist)
+		_, err = watchlistCtrler.Watchlist(ctx, "evening")
+		require.NoError(t, err)
+	})
+
+	t.Run("Watchlists can be created, replaced and deleted", func(t *testing.T) {
+		cacheClient := NewMockCacheClient()
+		watchlistCtrler := newWatchlistCtrler(t, &mockStockClient{}, cacheClient)
+
+		_, err := watchlistCtrler.SaveWatchlist(ctx, "tech", []string{"nvda"})
+		require.NoError(t, err)
+		_, err = watchlistCtrler.SaveWatchlist(ctx, "tech", []string{"NVDA", "AMD"})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"tech": ["NVDA", "AMD"]}`, cacheClient.Cache[watchlistsCacheKey])
+
+		// Other replicas see the same watchlists via the cache
+		otherCtrler := newWatchlistCtrler(t, &mockStockClient{}, cacheClient)
+		require.Equal(t, []*Watchlist{
+			{Name: "morning", Symbols: []string{"MSFT", "AAPL"}, ReadOnly: true},
+			{Name: "tech", Symbols: []string{"NVDA", "AMD"}},
+		}, otherCtrler.Watchlists(ctx))
+
+		require.NoError(t, otherCtrler.DeleteWatchlist(ctx, "tech"))
+		_, err = watchlistCtrler.Watchlist(ctx, "tech")
+		require.ErrorIs(t, err, ErrWatchlistNotFound)
+		require.ErrorIs(t, watchlistCtrler.DeleteWatchlist(ctx, "tech"), ErrWatchlistNotFound)
+	})
+
+	t.Run("Watchlists are kept in memory when caching is disabled", func(t *testing.T) {
+		cacheClient, _ := cache.NewNullClient("", 0)
+		watchlistCtrler := newWatchlistCtrler(t, &mockStockClient{}, cacheClient)
+
+		_, err := watchlistCtrler.SaveWatchlist(ctx, "tech", []string{"NVDA"})
+		require.NoError(t, err)
+		watchlist, err := watchlistCtrler.Watchlist(ctx, "tech")
+		require.NoError(t, err)
+		require.Equal(t, []string{"NVDA"}, watchlist.Symbols)
+	})
+
+	t.Run("Summary has a row per symbol", func(t *testing.T) {
+		stockClient := &mockStockClient{StockErrs: map[string]error{"AAPL": fmt.Errorf("upstream failure")}}
+		cacheClient, _ := cache.NewNullClient("", 0)
+		watchlistCtrler := newWatchlistCtrler(t, stockClient, cacheClient)
+
+		viewData, err := watchlistCtrler.Summary(ctx, "morning")
+		require.NoError(t, err)
+		require.Equal(t, 2, viewData["daysReq"])
+
+		rows := viewData["rows"].([]*WatchlistRow)
+		require.Len(t, rows, 2)
+		require.Equal(t, "MSFT", rows[0].Symbol)
+		require.Equal(t, 90.35, rows[0].Summary.LastClose)
+		require.Equal(t, 92.375, rows[0].Summary.AvgClose)
+		require.Empty(t, rows[0].Error)
+		require.Equal(t, "AAPL", rows[1].Symbol)
+		require.Nil(t, rows[1].Summary)
+		require.NotEmpty(t, rows[1].Error)
+
+		_, err = watchlistCtrler.Summary(ctx, "missing")
+		require.ErrorIs(t, err, ErrWatchlistNotFound)
+	})
+}
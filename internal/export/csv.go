@@ -0,0 +1,149 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"stockticker/internal/stockclient"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxPrecision = 10
+	// Rows are flushed in batches so large windows are streamed rather than buffered
+	flushEvery = 100
+)
+
+var (
+	ErrInvalidCSVOptions = errors.New("invalid csv options")
+
+	columns = map[string]func(opts *CSVOptions, dayData *stockclient.DayData) string{
+		"date": func(opts *CSVOptions, dayData *stockclient.DayData) string {
+			return opts.formatDate(dayData.Date)
+		},
+		"close": func(opts *CSVOptions, dayData *stockclient.DayData) string {
+			return opts.formatFloat(dayData.Close)
+		},
+		"dividend": func(opts *CSVOptions, dayData *stockclient.DayData) string {
+			return opts.formatFloat(dayData.Dividend)
+		},
+		"volume": func(opts *CSVOptions, dayData *stockclient.DayData) string {
+			// Volumes are whole numbers apart from cryptocurrencies so aren't rounded
+			return strconv.FormatFloat(dayData.Volume, 'f', -1, 64)
+		},
+		"split_coefficient": func(opts *CSVOptions, dayData *stockclient.DayData) string {
+			if dayData.SplitCoefficient == 0 {
+				return ""
+			}
+			return opts.formatFloat(dayData.SplitCoefficient)
+		},
+	}
+
+	dateFormats = map[string]string{
+		"iso": time.DateOnly,
+		"us":  "01/02/2006",
+		"eu":  "02/01/2006",
+		// Seconds since the Unix epoch
+		"unix": "",
+	}
+
+	delimiters = map[string]rune{
+		"comma":     ',',
+		"semicolon": ';',
+		"tab":       '\t',
+	}
+)
+
+type CSVOptions struct {
+	columns    []string
+	dateFormat string
+	// -1 uses the fewest digits necessary to represent each value exactly
+	precision int
+	delimiter rune
+}
+
+// NewCSVOptions validates the export options. columns is a comma separated list of date, close, volume, dividend
+// and split_coefficient, dateFormat is one of iso, us, eu or unix and delimiter is one of comma, semicolon or tab. Empty
+// values use the defaults of date,close, iso and comma. precision is the number of decimal places, or -1 for as many
+// as necessary
+func NewCSVOptions(cols string, dateFormat string, precision int, delimiter string) (*CSVOptions, error) {
+	opts := &CSVOptions{
+		columns:    []string{"date", "close"},
+		dateFormat: "iso",
+		precision:  precision,
+		delimiter:  ',',
+	}
+
+	if cols != "" {
+		opts.columns = []string{}
+		for _, col := range strings.Split(cols, ",") {
+			col = strings.ToLower(strings.TrimSpace(col))
+			if _, ok := columns[col]; !ok {
+				return nil, fmt.Errorf("%w: unknown column '%s'", ErrInvalidCSVOptions, col)
+			}
+			if slices.Contains(opts.columns, col) {
+				return nil, fmt.Errorf("%w: column '%s' is repeated", ErrInvalidCSVOptions, col)
+			}
+			opts.columns = append(opts.columns, col)
+		}
+	}
+
+	if dateFormat != "" {
+		if _, ok := dateFormats[dateFormat]; !ok {
+			return nil, fmt.Errorf("%w: date format must be one of iso, us, eu or unix", ErrInvalidCSVOptions)
+		}
+		opts.dateFormat = dateFormat
+	}
+
+	if precision < -1 || precision > maxPrecision {
+		return nil, fmt.Errorf("%w: precision must be between -1 and %d, where -1 means full precision", ErrInvalidCSVOptions, maxPrecision)
+	}
+
+	if delimiter != "" {
+		d, ok := delimiters[delimiter]
+		if !ok {
+			return nil, fmt.Errorf("%w: delimiter must be one of comma, semicolon or tab", ErrInvalidCSVOptions)
+		}
+		opts.delimiter = d
+	}
+	return opts, nil
+}
+
+// WriteCSV writes a header row followed by a row per day of dailyData
+func WriteCSV(w io.Writer, dailyData []*stockclient.DayData, opts *CSVOptions) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = opts.delimiter
+
+	if err := writer.Write(opts.columns); err != nil {
+		return err
+	}
+	row := make([]string, len(opts.columns))
+	for i, dayData := range dailyData {
+		for j, col := range opts.columns {
+			row[j] = columns[col](opts, dayData)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+		if i%flushEvery == flushEvery-1 {
+			writer.Flush()
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (opts *CSVOptions) formatDate(date time.Time) string {
+	if opts.dateFormat == "unix" {
+		return strconv.FormatInt(date.Unix(), 10)
+	}
+	return date.Format(dateFormats[opts.dateFormat])
+}
+
+func (opts *CSVOptions) formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', opts.precision, 64)
+}
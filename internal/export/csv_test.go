@@ -0,0 +1,93 @@
+package export
+
+import (
+	"bytes"
+	"stockticker/internal/stockclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var dailyData = []*stockclient.DayData{
+	{Date: time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC), Close: 129.04, Volume: 225702700, Dividend: 0.2075, SplitCoefficient: 4},
+	{Date: time.Date(2020, 8, 28, 0, 0, 0, 0, time.UTC), Close: 124.8075, Volume: 187629916},
+}
+
+func TestWriteCSV(t *testing.T) {
+	tests := map[string]struct {
+		columns    string
+		dateFormat string
+		precision  int
+		delimiter  string
+		expected   string
+		err        string
+	}{
+		"defaults": {
+			precision: -1,
+			expected:  "date,close\n2020-08-31,129.04\n2020-08-28,124.8075\n",
+		},
+		"all columns with fixed precision": {
+			columns:   "date, close,VOLUME,dividend,split_coefficient",
+			precision: 2,
+			expected:  "date,close,volume,dividend,split_coefficient\n2020-08-31,129.04,225702700,0.21,4.00\n2020-08-28,124.81,187629916,0.00,\n",
+		},
+		"us dates separated by semicolons": {
+			columns:    "close,date",
+			dateFormat: "us",
+			precision:  0,
+			delimiter:  "semicolon",
+			expected:   "close;date\n129;08/31/2020\n125;08/28/2020\n",
+		},
+		"unix dates": {
+			columns:    "date",
+			dateFormat: "unix",
+			precision:  -1,
+			expected:   "date\n1598832000\n1598572800\n",
+		},
+		"unknown column": {
+			columns:   "date,open",
+			precision: -1,
+			err:       "invalid csv options: unknown column 'open'",
+		},
+		"repeated column": {
+			columns:   "close,close",
+			precision: -1,
+			err:       "invalid csv options: column 'close' is repeated",
+		},
+		"unknown date format": {
+			dateFormat: "2006-01-02",
+			precision:  -1,
+			err:        "invalid csv options: date format must be one of iso, us, eu or unix",
+		},
+		"precision too high": {
+			precision: 11,
+			err:       "invalid csv options: precision must be between -1 and 10, where -1 means full precision",
+		},
+		"precision too low": {
+			precision: -2,
+			err:       "invalid csv options: precision must be between -1 and 10, where -1 means full precision",
+		},
+		"unknown delimiter": {
+			precision: -1,
+			delimiter: "|",
+			err:       "invalid csv options: delimiter must be one of comma, semicolon or tab",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts, err := NewCSVOptions(tt.columns, tt.dateFormat, tt.precision, tt.delimiter)
+			if tt.err != "" {
+				require.ErrorIs(t, err, ErrInvalidCSVOptions)
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, WriteCSV(&buf, dailyData, opts))
+			require.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
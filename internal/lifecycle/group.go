@@ -0,0 +1,117 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type component struct {
+	name string
+	run  func() error
+	stop func(ctx context.Context) error
+	// Closed when run returns
+	done chan struct{}
+}
+
+// Group runs servers and background workers together. If any of them fails, or the group is asked to stop, they're
+// all stopped in the reverse of the order they were added. The zero value is ready to use
+type Group struct {
+	components []*component
+}
+
+// Add adds a component. run blocks until the component fails or is stopped and may be nil for components that only
+// need cleaning up, such as clients. stop must make run return and may be nil if there's nothing to stop
+func (g *Group) Add(name string, run func() error, stop func(ctx context.Context) error) {
+	g.components = append(g.components, &component{name: name, run: run, stop: stop})
+}
+
+// AddContext adds a component that runs until its context is cancelled
+func (g *Group) AddContext(name string, run func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g.Add(name, func() error {
+		run(ctx)
+		return nil
+	}, func(context.Context) error {
+		cancel()
+		return nil
+	})
+}
+
+// Run starts every component and waits until ctx is done or a component fails. Every component is then stopped, in
+// order, within timeout. The error is the first failure, joined with any errors from stopping
+func (g *Group) Run(ctx context.Context, timeout time.Duration) error {
+	failed := make(chan error, len(g.components))
+	for _, c := range g.components {
+		c.done = make(chan struct{})
+		if c.run == nil {
+			close(c.done)
+			continue
+		}
+		go func() {
+			defer close(c.done)
+			err := c.run()
+			if err == nil {
+				err = errors.New("stopped unexpectedly")
+			}
+			// Only read before shutting down, when any return is unexpected
+			failed <- fmt.Errorf("%s: %w", c.name, err)
+		}()
+	}
+
+	errs := []error{}
+	select {
+	case <-ctx.Done():
+		log.Info("Shutting down")
+	case err := <-failed:
+		log.Errorf("Shutting down after a failure: %v", err)
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, g.stop(timeout)...)
+	return errors.Join(errs...)
+}
+
+// Stop stops every component in reverse order within timeout, without running them. It cleans up the components that
+// were added when something fails before the group is run
+func (g *Group) Stop(timeout time.Duration) error {
+	return errors.Join(g.stop(timeout)...)
+}
+
+func (g *Group) stop(timeout time.Duration) []error {
+	stopCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	errs := []error{}
+	for i := len(g.components) - 1; i >= 0; i-- {
+		c := g.components[i]
+		log.Debugf("Stopping %s", c.name)
+		if c.stop != nil {
+			if err := c.stop(stopCtx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to stop %s: %w", c.name, err))
+			}
+		}
+		// Components that were never run have nothing to wait for
+		if c.done != nil && !waitFor(stopCtx, c.done) {
+			errs = append(errs, fmt.Errorf("%s didn't stop within %s", c.name, timeout))
+		}
+	}
+	return errs
+}
+
+// waitFor reports whether done was closed before ctx was done
+func waitFor(ctx context.Context, done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+	}
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// recorder records the order components are stopped in
+type recorder struct {
+	mu      sync.Mutex
+	stopped []string
+}
+
+func (r *recorder) add(g *Group, name string) {
+	stop := make(chan struct{})
+	g.Add(name, func() error {
+		<-stop
+		return nil
+	}, func(context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.stopped = append(r.stopped, name)
+		close(stop)
+		return nil
+	})
+}
+
+func TestGroup(t *testing.T) {
+	t.Run("Components are stopped in reverse order when the context is done", func(t *testing.T) {
+		var g Group
+		r := &recorder{}
+		r.add(&g, "first")
+		g.AddContext("worker", func(ctx context.Context) {
+			<-ctx.Done()
+		})
+		r.add(&g, "last")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.NoError(t, g.Run(ctx, time.Second))
+		require.Equal(t, []string{"last", "first"}, r.stopped)
+	})
+
+	t.Run("The first failure stops everything", func(t *testing.T) {
+		var g Group
+		r := &recorder{}
+		r.add(&g, "server")
+		failure := errors.New("address in use")
+		g.Add("failing", func() error {
+			return failure
+		}, nil)
+		closed := false
+		g.Add("client", nil, func(context.Context) error {
+			closed = true
+			return nil
+		})
+
+		err := g.Run(context.Background(), time.Second)
+		require.ErrorIs(t, err, failure)
+		require.EqualError(t, err, "failing: address in use")
+		require.Equal(t, []string{"server"}, r.stopped)
+		require.True(t, closed)
+	})
+
+	t.Run("Components that return early fail", func(t *testing.T) {
+		var g Group
+		g.Add("early", func() error {
+			return nil
+		}, nil)
+		require.EqualError(t, g.Run(context.Background(), time.Second), "early: stopped unexpectedly")
+	})
+
+	t.Run("Shutdown is limited by the timeout", func(t *testing.T) {
+		var g Group
+		block := make(chan struct{})
+		defer close(block)
+		g.Add("stuck", func() error {
+			<-block
+			return nil
+		}, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		r := &recorder{}
+		r.add(&g, "server")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := g.Run(ctx, 10*time.Millisecond)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.ErrorContains(t, err, "stuck didn't stop within 10ms")
+		require.Equal(t, []string{"server"}, r.stopped)
+	})
+
+	t.Run("Components can be stopped without being run", func(t *testing.T) {
+		var g Group
+		r := &recorder{}
+		r.add(&g, "first")
+		g.AddContext("worker", func(ctx context.Context) {
+			t.Error("The worker was run")
+		})
+		r.add(&g, "last")
+
+		require.NoError(t, g.Stop(time.Second))
+		require.Equal(t, []string{"last", "first"}, r.stopped)
+	})
+}
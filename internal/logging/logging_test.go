@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	out, formatter := log.StandardLogger().Out, log.StandardLogger().Formatter
+	log.SetOutput(&buf)
+	log.SetFormatter(&log.JSONFormatter{})
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		log.SetFormatter(formatter)
+	})
+
+	ctx := context.Background()
+	require.Empty(t, RequestID(ctx))
+	FromContext(ctx).Info("no request")
+
+	ctx = WithRequestID(ctx, "abc123")
+	require.Equal(t, "abc123", RequestID(ctx))
+	FromContext(ctx).Info("in a request")
+
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	}))
+	FromContext(ctx).Info("in a trace")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	var first, second, third map[string]any
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	require.NoError(t, json.Unmarshal(lines[2], &third))
+	require.NotContains(t, first, "request_id")
+	require.Equal(t, "in a request", second["msg"])
+	require.Equal(t, "abc123", second["request_id"])
+	require.NotContains(t, second, "trace_id")
+	require.Equal(t, "abc123", third["request_id"])
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", third["trace_id"])
+}
@@ -1,12 +1,12 @@
 package monitoring
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof" //nolint:gosec
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type PprofServer struct {
@@ -16,19 +16,19 @@ type PprofServer struct {
 func NewPprofServer(ip string, port int) (*PprofServer, error) {
 	server := &PprofServer{
 		httpServer: &http.Server{
-			Addr:        fmt.Sprintf("%s:%d", ip, port),
+			Addr:        net.JoinHostPort(ip, fmt.Sprint(port)),
 			ReadTimeout: 180 * time.Second,
 		},
 	}
 	return server, nil
 }
 
-func (ps *PprofServer) Listen() error {
-	go func() {
-		err := ps.httpServer.ListenAndServe()
-		if err != nil {
-			log.Fatalf("Could not start pprof server: %v\n", err)
-		}
-	}()
-	return nil
+// ListenAndServe serves requests until Stop is called
+func (ps *PprofServer) ListenAndServe() error {
+	return listenAndServe(ps.httpServer, "Pprof")
+}
+
+// Stop shuts the server down, waiting for in-flight requests to complete until ctx is done
+func (ps *PprofServer) Stop(ctx context.Context) error {
+	return ps.httpServer.Shutdown(ctx)
 }
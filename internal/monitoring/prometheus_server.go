@@ -1,12 +1,12 @@
 package monitoring
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -16,11 +16,11 @@ type PromServer struct {
 
 func NewPrometheusServer(ip string, port int) (*PromServer, error) {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", MetricsHandler())
 
 	server := &PromServer{
 		httpServer: &http.Server{
-			Addr:        fmt.Sprintf("%s:%d", ip, port),
+			Addr:        net.JoinHostPort(ip, fmt.Sprint(port)),
 			Handler:     mux,
 			ReadTimeout: 30 * time.Second,
 		},
@@ -28,12 +28,17 @@ func NewPrometheusServer(ip string, port int) (*PromServer, error) {
 	return server, nil
 }
 
-func (ps *PromServer) Listen() error {
-	go func() {
-		err := ps.httpServer.ListenAndServe()
-		if err != nil {
-			log.Fatalf("Could not start prometheus metrics server: %v\n", err)
-		}
-	}()
-	return nil
+// MetricsHandler serves the metrics in the Prometheus exposition format, e.g. on another server's /metrics
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ListenAndServe serves requests until Stop is called
+func (ps *PromServer) ListenAndServe() error {
+	return listenAndServe(ps.httpServer, "Prometheus")
+}
+
+// Stop shuts the server down, waiting for in-flight requests to complete until ctx is done
+func (ps *PromServer) Stop(ctx context.Context) error {
+	return ps.httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,23 @@
+package monitoring
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// listenAndServe serves requests until the server is shut down, which isn't an error. It logs the address once it's
+// bound, so nothing is logged if binding fails
+func listenAndServe(httpServer *http.Server, name string) error {
+	listener, err := net.Listen("tcp", httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	log.Infof("%s HTTP server listening on %s", name, listener.Addr())
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Tracer exports the spans recorded by every package's OpenTelemetry tracer
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+}
+
+// NewTracer exports spans in batches to the OTLP/HTTP collector at endpoint, e.g. http://localhost:4318, sampling
+// sampleRatio of new traces. It's installed as the global tracer provider along with W3C trace context propagation, so
+// traces continue from incoming requests
+func NewTracer(serviceName, endpoint string, sampleRatio float64) (*Tracer, error) {
+	tracesURL, err := url.JoinPath(endpoint, "/v1/traces")
+	if err != nil {
+		return nil, fmt.Errorf("invalid tracing endpoint: %w", err)
+	}
+	// Nothing is sent until spans are exported, so this doesn't fail if the collector is unavailable
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(tracesURL))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return &Tracer{provider: provider}, nil
+}
+
+// Stop exports any spans that are still buffered until ctx is done
+func (t *Tracer) Stop(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
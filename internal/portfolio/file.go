@@ -0,0 +1,16 @@
+package portfolio
+
+import "stockticker/internal/datafile"
+
+type portfolioFile struct {
+	Positions []Position `yaml:"positions"`
+}
+
+// LoadPositions reads positions from a YAML, JSON or TOML file with a top level positions list
+func LoadPositions(path string) ([]Position, error) {
+	file := portfolioFile{}
+	if err := datafile.Load(path, &file); err != nil {
+		return nil, err
+	}
+	return file.Positions, nil
+}
@@ -0,0 +1,94 @@
+package portfolio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPositions(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		contents  string
+		positions []Position
+		valid     bool
+	}{
+		{
+			name: "YAML",
+			file: "portfolio.yaml",
+			contents: `positions:
+  - symbol: MSFT
+    quantity: 10
+    costBasis: 1000
+  - symbol: VOD.LON
+    quantity: 100
+    costBasis: 75.5
+    currency: GBP
+`,
+			positions: []Position{
+				{Symbol: "MSFT", Quantity: 10, CostBasis: 1000},
+				{Symbol: "VOD.LON", Quantity: 100, CostBasis: 75.5, Currency: "GBP"},
+			},
+			valid: true,
+		},
+		{
+			name:      "JSON",
+			file:      "portfolio.json",
+			contents:  `{"positions": [{"symbol": "MSFT", "quantity": 0.5, "costBasis": 200}]}`,
+			positions: []Position{{Symbol: "MSFT", Quantity: 0.5, CostBasis: 200}},
+			valid:     true,
+		},
+		{
+			name:      "TOML",
+			file:      "portfolio.toml",
+			contents:  "[[positions]]\nsymbol = \"MSFT\"\nquantity = 10\ncostBasis = 1000\n",
+			positions: []Position{{Symbol: "MSFT", Quantity: 10, CostBasis: 1000}},
+			valid:     true,
+		},
+		{
+			name:      "No positions",
+			file:      "portfolio.yaml",
+			contents:  "positions: []\n",
+			positions: []Position{},
+			valid:     true,
+		},
+		{
+			name:     "Unknown field",
+			file:     "portfolio.yaml",
+			contents: "positions:\n  - symbol: MSFT\n    quantity: 10\n    cost: 1000\n",
+		},
+		{
+			name:     "Wrong type",
+			file:     "portfolio.json",
+			contents: `{"positions": [{"symbol": "MSFT", "quantity": "ten"}]}`,
+		},
+		{
+			name:     "Empty file",
+			file:     "portfolio.yaml",
+			contents: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			require.NoError(t, os.WriteFile(path, []byte(test.contents), 0o600))
+
+			positions, err := LoadPositions(path)
+			if !test.valid {
+				require.ErrorContains(t, err, "failed to parse "+path)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.positions, positions)
+		})
+	}
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := LoadPositions(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,45 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const DefaultCurrency = "USD"
+
+var (
+	ErrInvalidPosition = errors.New("invalid portfolio position")
+
+	currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+type Position struct {
+	Symbol   string  `json:"symbol" yaml:"symbol"`
+	Quantity float64 `json:"quantity" yaml:"quantity"`
+	// The total amount paid for the position
+	CostBasis float64 `json:"costBasis" yaml:"costBasis"`
+	// The ISO 4217 code of the currency the symbol is priced in. Defaults to USD
+	Currency string `json:"currency,omitempty" yaml:"currency,omitempty"`
+}
+
+// Validate checks the position is usable and normalises its currency. The symbol is validated separately as it's
+// normalised by the caller
+func (p *Position) Validate() error {
+	if p.Quantity <= 0 {
+		return fmt.Errorf("%w: '%s' quantity must be greater than zero", ErrInvalidPosition, p.Symbol)
+	}
+	if p.CostBasis < 0 {
+		return fmt.Errorf("%w: '%s' cost basis can't be negative", ErrInvalidPosition, p.Symbol)
+	}
+
+	p.Currency = strings.ToUpper(strings.TrimSpace(p.Currency))
+	if p.Currency == "" {
+		p.Currency = DefaultCurrency
+	}
+	if !currencyRegexp.MatchString(p.Currency) {
+		return fmt.Errorf("%w: '%s' currency must be a three letter code", ErrInvalidPosition, p.Symbol)
+	}
+	return nil
+}
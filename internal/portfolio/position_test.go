@@ -0,0 +1,38 @@
+package portfolio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		position Position
+		currency string
+		valid    bool
+	}{
+		{"Default currency", Position{Symbol: "MSFT", Quantity: 10, CostBasis: 1000}, DefaultCurrency, true},
+		{"Currency normalised", Position{Symbol: "VOD.LON", Quantity: 1, CostBasis: 1, Currency: " gbp "}, "GBP", true},
+		{"Fractional quantity", Position{Symbol: "MSFT", Quantity: 0.5, CostBasis: 200}, DefaultCurrency, true},
+		{"Zero cost basis", Position{Symbol: "MSFT", Quantity: 1}, DefaultCurrency, true},
+		{"Zero quantity", Position{Symbol: "MSFT", CostBasis: 1}, "", false},
+		{"Negative quantity", Position{Symbol: "MSFT", Quantity: -1, CostBasis: 1}, "", false},
+		{"Negative cost basis", Position{Symbol: "MSFT", Quantity: 1, CostBasis: -1}, "", false},
+		{"Currency too long", Position{Symbol: "MSFT", Quantity: 1, Currency: "USDT"}, "", false},
+		{"Currency not letters", Position{Symbol: "MSFT", Quantity: 1, Currency: "U5D"}, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.position.Validate()
+			if !test.valid {
+				require.ErrorIs(t, err, ErrInvalidPosition)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.currency, test.position.Currency)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"net/http"
+
+	"stockticker/internal/alerts"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) alertRules(c *gin.Context) {
+	c.JSON(http.StatusOK, s.alertCtrler.Rules(c.Request.Context()))
+}
+
+func (s *Server) saveAlertRule(c *gin.Context) {
+	rule := alerts.Rule{}
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := s.alertCtrler.SaveRule(c.Request.Context(), c.Param("id"), rule)
+	if err != nil {
+		jsonError(c, err, "save alert rule")
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+func (s *Server) deleteAlertRule(c *gin.Context) {
+	err := s.alertCtrler.DeleteRule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		jsonError(c, err, "delete alert rule")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
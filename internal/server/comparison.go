@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stockticker/internal/controller"
+	"stockticker/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultComparisonWindow = 30
+
+func (s *Server) comparisonView(c *gin.Context) {
+	comparison, err := s.compare(c)
+	if err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			logging.FromContext(c.Request.Context()).Errorf("Failed to compare symbols: %v", err)
+			c.HTML(status, "500.tmpl", gin.H{})
+			return
+		}
+		c.String(status, err.Error())
+		return
+	}
+	c.HTML(http.StatusOK, s.comparisonCtrler.ViewTemplate(), comparison)
+}
+
+func (s *Server) comparison(c *gin.Context) {
+	comparison, err := s.compare(c)
+	if err != nil {
+		jsonError(c, err, "compare symbols")
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+// compare compares the comma separated symbols in the symbols query parameter over the number of days in the window
+// query parameter
+func (s *Server) compare(c *gin.Context) (*controller.Comparison, error) {
+	window := defaultComparisonWindow
+	if w := c.Query("window"); w != "" {
+		var err error
+		if window, err = strconv.Atoi(w); err != nil {
+			// Out of range windows are rejected by the controller
+			window = 0
+		}
+	}
+	return s.comparisonCtrler.Compare(c.Request.Context(), splitSymbols(c.Query("symbols")), window)
+}
+
+// splitSymbols splits a comma separated list of symbols, ignoring empty entries
+func splitSymbols(s string) []string {
+	symbols := []string{}
+	for _, symbol := range strings.Split(s, ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
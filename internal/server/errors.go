@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"stockticker/internal/alerts"
+	"stockticker/internal/chart"
+	"stockticker/internal/controller"
+	"stockticker/internal/export"
+	"stockticker/internal/logging"
+	"stockticker/internal/stockclient"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps controller errors to HTTP status codes. Anything unrecognised is an internal server error
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrInvalidSymbol),
+		errors.Is(err, controller.ErrInvalidSearchQuery),
+		errors.Is(err, controller.ErrTooManySymbols),
+		errors.Is(err, controller.ErrInvalidWatchlist),
+		errors.Is(err, controller.ErrInvalidComparison),
+		errors.Is(err, controller.ErrInvalidCurrency),
+		errors.Is(err, controller.ErrInvalidSparkline),
+		errors.Is(err, stockclient.ErrUnsupportedAssetClass),
+		errors.Is(err, alerts.ErrInvalidRule),
+		errors.Is(err, export.ErrInvalidCSVOptions),
+		errors.Is(err, chart.ErrInvalidMovingAverage):
+		return http.StatusBadRequest
+	case errors.Is(err, controller.ErrWatchlistNotFound),
+		errors.Is(err, controller.ErrAlertRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, controller.ErrWatchlistReadOnly),
+		errors.Is(err, controller.ErrAlertRuleReadOnly):
+		return http.StatusConflict
+	case errors.Is(err, stockclient.ErrRateLimited):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// jsonError responds with err if it was caused by the request. Otherwise err is logged and a generic message based on
+// action, e.g. "retrieve quote", is returned
+func jsonError(c *gin.Context, err error, action string) {
+	status := errorStatus(err)
+	if status != http.StatusInternalServerError {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Errorf("Failed to %s: %v", action, err)
+	c.JSON(status, gin.H{"error": "failed to " + action})
+}
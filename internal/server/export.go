@@ -0,0 +1,57 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stockticker/internal/export"
+
+	"github.com/gin-gonic/gin"
+)
+
+const mimeCSV = "text/csv"
+
+// dailyCSV streams the daily prices as CSV, downloaded as a file by browsers
+func (s *Server) dailyCSV(c *gin.Context) {
+	s.writeDailyCSV(c, true)
+}
+
+func (s *Server) writeDailyCSV(c *gin.Context, attachment bool) {
+	opts, err := csvOptions(c)
+	if err != nil {
+		jsonError(c, err, "export daily prices")
+		return
+	}
+	prices, err := s.stockCtrler.DailyPrices(c.Request.Context(), c.Param("symbol"), priceOptions(c))
+	if err != nil {
+		jsonError(c, err, "retrieve daily prices")
+		return
+	}
+
+	c.Header("Content-Type", mimeCSV+"; charset=utf-8")
+	if attachment {
+		// Asset class prefixes contain a colon, which isn't allowed in file names on Windows
+		filename := strings.ReplaceAll(prices.Symbol, ":", "-")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-daily.csv"`, filename))
+	}
+	c.Status(http.StatusOK)
+	if err := export.WriteCSV(c.Writer, prices.DailyData, opts); err != nil {
+		// The status has already been sent so the best that can be done is to stop writing
+		_ = c.Error(err)
+	}
+}
+
+// csvOptions reads the columns, date_format, precision and delimiter query parameters
+func csvOptions(c *gin.Context) (*export.CSVOptions, error) {
+	precision := -1
+	if p := c.Query("precision"); p != "" {
+		var err error
+		precision, err = strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: precision must be a number", export.ErrInvalidCSVOptions)
+		}
+	}
+	return export.NewCSVOptions(c.Query("columns"), c.Query("date_format"), precision, c.Query("delimiter"))
+}
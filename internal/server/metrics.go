@@ -0,0 +1,56 @@
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Requests are labelled by route template, e.g. /api/v1/stocks/:symbol/quote, rather than path so the number of
+// series is bounded. Requests that don't match a route are labelled unmatched
+var (
+	httpRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "stockticker",
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests handled",
+		},
+		[]string{"route", "status_class"},
+	)
+
+	httpRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "stockticker",
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Bucketed histogram of HTTP request timings. Streams are timed until they close",
+
+			// 5ms to 10s
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "status_class"},
+	)
+
+	httpRequestsInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "stockticker",
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests being handled, including open streams",
+		},
+		[]string{"route"},
+	)
+
+	httpResponseSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "stockticker",
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "Bucketed histogram of HTTP response body sizes",
+
+			// 100B to 10MB
+			Buckets: prometheus.ExponentialBuckets(100, 10, 6),
+		},
+		[]string{"route", "status_class"},
+	)
+)
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"stockticker/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Incoming request IDs are only used if they're safe to log
+var requestIDRegexp = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// requestID adds the request's ID to its context and response headers. The ID is taken from the X-Request-ID header,
+// so requests can be traced through proxies, or generated if there isn't a valid one
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !requestIDRegexp.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	// Never returns an error
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// tracing starts a span for each request, continuing the trace from the W3C traceparent header if there is one.
+// Health checks aren't traced as they're frequent
+func tracing() gin.HandlerFunc {
+	return otelgin.Middleware("stockticker", otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !isHealthCheck(c)
+	}))
+}
+
+func isHealthCheck(c *gin.Context) bool {
+	switch c.FullPath() {
+	case "/api/v1/liveness", "/api/v1/readiness":
+		return true
+	}
+	return false
+}
+
+// requestMetrics records the rate, errors and duration of requests to each route
+func requestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		inFlight := httpRequestsInFlight.WithLabelValues(route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		c.Next()
+
+		labels := []string{route, fmt.Sprintf("%dxx", c.Writer.Status()/100)}
+		httpRequests.WithLabelValues(labels...).Inc()
+		httpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		httpResponseSize.WithLabelValues(labels...).Observe(float64(max(c.Writer.Size(), 0)))
+	}
+}
+
+// accessLog logs each request once it's complete. Health checks are logged at debug level as they're frequent
+func accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		fields := log.Fields{
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"bytes":      max(c.Writer.Size(), 0),
+			"client_ip":  c.ClientIP(),
+		}
+		if symbol := c.Param("symbol"); symbol != "" {
+			fields["symbol"] = symbol
+		}
+		entry := logging.FromContext(c.Request.Context()).WithFields(fields)
+
+		if isHealthCheck(c) {
+			entry.Debug("Request handled")
+		} else {
+			entry.Info("Request handled")
+		}
+	}
+}
+
+// requireToken rejects requests that don't have the bearer token in their Authorization header. Every request is
+// rejected if token is empty
+func requireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "changes are disabled as no API token is configured"})
+			return
+		}
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="stockticker"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "a valid API token is required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+
+	"stockticker/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	out, formatter := log.StandardLogger().Out, log.StandardLogger().Formatter
+	log.SetOutput(&buf)
+	log.SetFormatter(&log.JSONFormatter{})
+	t.Cleanup(func() {
+		log.SetOutput(out)
+		log.SetFormatter(formatter)
+	})
+
+	router := gin.New()
+	router.Use(requestID(), accessLog())
+	router.GET("/api/v1/stocks/:symbol/quote", func(c *gin.Context) {
+		logging.FromContext(c.Request.Context()).Warn("Failed to get quote")
+		c.String(http.StatusTeapot, "quote")
+	})
+
+	// logLines returns the JSON log lines written while handling a request
+	logLines := func(t *testing.T, requestID string) (*httptest.ResponseRecorder, []map[string]any) {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks/MSFT/quote", nil)
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		router.ServeHTTP(w, req)
+
+		lines := []map[string]any{}
+		for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+			var fields map[string]any
+			require.NoError(t, json.Unmarshal(line, &fields))
+			lines = append(lines, fields)
+		}
+		return w, lines
+	}
+
+	t.Run("Incoming request IDs are used", func(t *testing.T) {
+		w, lines := logLines(t, "abc-123")
+		require.Equal(t, "abc-123", w.Header().Get("X-Request-ID"))
+		require.Len(t, lines, 2)
+		require.Equal(t, "Failed to get quote", lines[0]["msg"])
+		require.Equal(t, "abc-123", lines[0]["request_id"])
+
+		access := lines[1]
+		require.Equal(t, "Request handled", access["msg"])
+		require.Equal(t, "abc-123", access["request_id"])
+		require.Equal(t, "GET", access["method"])
+		require.Equal(t, "/api/v1/stocks/MSFT/quote", access["path"])
+		require.Equal(t, float64(http.StatusTeapot), access["status"])
+		require.Equal(t, float64(5), access["bytes"])
+		require.Equal(t, "MSFT", access["symbol"])
+		require.Contains(t, access, "latency_ms")
+	})
+
+	t.Run("Request IDs are generated if missing or invalid", func(t *testing.T) {
+		for _, incoming := range []string{"", "not valid"} {
+			w, lines := logLines(t, incoming)
+			id := w.Header().Get("X-Request-ID")
+			require.Len(t, id, 32)
+			require.Equal(t, id, lines[1]["request_id"])
+		}
+	})
+}
+
+func TestTracing(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	var traceID string
+	router := gin.New()
+	router.Use(requestID(), tracing(), accessLog())
+	router.GET("/api/v1/stocks/:symbol/quote", func(c *gin.Context) {
+		traceID = logging.FromContext(c.Request.Context()).Data["trace_id"].(string)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/api/v1/liveness", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	t.Run("Traces continue from the traceparent header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stocks/MSFT/quote", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		require.Equal(t, "/api/v1/stocks/:symbol/quote", spans[0].Name())
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	})
+
+	t.Run("Health checks aren't traced", func(t *testing.T) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/liveness", nil))
+		require.Len(t, recorder.Ended(), 1)
+	})
+}
+
+func TestRequestMetrics(t *testing.T) {
+	route := "/api/v1/test/:symbol"
+	router := gin.New()
+	router.Use(requestMetrics())
+	router.GET(route, func(c *gin.Context) {
+		require.Equal(t, float64(1), testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(route)))
+		if c.Param("symbol") == "MSFT" {
+			c.String(http.StatusOK, "quote")
+			return
+		}
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/api/v1/test/MSFT", "/api/v1/test/AAPL", "/api/v1/test/IBM", "/not-a-route"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Requests are labelled by route rather than path
+	require.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues(route, "2xx")))
+	require.Equal(t, float64(2), testutil.ToFloat64(httpRequests.WithLabelValues(route, "4xx")))
+	require.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("unmatched", "4xx")))
+	require.Equal(t, float64(0), testutil.ToFloat64(httpRequestsInFlight.WithLabelValues(route)))
+	require.Equal(t, 1, testutil.CollectAndCount(httpRequestDuration.WithLabelValues(route, "2xx").(prometheus.Histogram)))
+
+	sizes := &dto.Metric{}
+	require.NoError(t, httpResponseSize.WithLabelValues(route, "2xx").(prometheus.Histogram).Write(sizes))
+	require.Equal(t, uint64(1), sizes.Histogram.GetSampleCount())
+	require.Equal(t, float64(5), sizes.Histogram.GetSampleSum())
+}
+
+func TestRequireToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{"Valid token", "secret", "Bearer secret", http.StatusOK},
+		{"Wrong token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"Wrong scheme", "secret", "Basic secret", http.StatusUnauthorized},
+		{"Missing token", "secret", "", http.StatusUnauthorized},
+		{"No token configured", "", "Bearer ", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			router.PUT("/api/v1/watchlists/:name", requireToken(test.token), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/watchlists/tech", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			router.ServeHTTP(w, req)
+			require.Equal(t, test.status, w.Code)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) portfolioView(c *gin.Context) {
+	c.HTML(http.StatusOK, s.portfolioCtrler.ViewTemplate(), s.portfolioCtrler.Valuation(c.Request.Context()))
+}
+
+func (s *Server) portfolio(c *gin.Context) {
+	c.JSON(http.StatusOK, s.portfolioCtrler.Valuation(c.Request.Context()))
+}
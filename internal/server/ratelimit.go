@@ -0,0 +1,81 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Symbol searches use the upstream quota on a cache miss, so anonymous clients are limited to typical typing
+	searchRequestsPerMinute = 30
+	searchBurst             = 10
+	// Clients idle for longer than this are forgotten. Must be at least the time a limiter takes to refill
+	clientIdleTimeout = 5 * time.Minute
+)
+
+// clientLimiter rate limits each client, identified by IP address, separately
+type clientLimiter struct {
+	limit rate.Limit
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientRate
+	lastPrune time.Time
+}
+
+type clientRate struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newClientLimiter(requestsPerMinute, burst int) *clientLimiter {
+	return &clientLimiter{
+		limit:   rate.Every(time.Minute / time.Duration(requestsPerMinute)),
+		burst:   burst,
+		now:     time.Now,
+		clients: make(map[string]*clientRate),
+	}
+}
+
+// allow reports whether client can make a request now
+func (l *clientLimiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastPrune) > clientIdleTimeout {
+		for c, cr := range l.clients {
+			if now.Sub(cr.lastSeen) > clientIdleTimeout {
+				delete(l.clients, c)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	cr, ok := l.clients[client]
+	if !ok {
+		cr = &clientRate{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = cr
+	}
+	cr.lastSeen = now
+	return cr.limiter.AllowN(now, 1)
+}
+
+// middleware rejects requests from clients over their limit with 429 Too Many Requests
+func (l *clientLimiter) middleware() gin.HandlerFunc {
+	retryAfter := strconv.Itoa(int(math.Ceil(time.Duration(float64(time.Second) / float64(l.limit)).Seconds())))
+	return func(c *gin.Context) {
+		if !l.allow(c.ClientIP()) {
+			c.Header("Retry-After", retryAfter)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
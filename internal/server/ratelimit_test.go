@@ -0,0 +1,31 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	limiter := newClientLimiter(60, 2)
+	limiter.now = func() time.Time { return now }
+
+	t.Run("Each client has its own limit", func(t *testing.T) {
+		require.True(t, limiter.allow("10.0.0.1"))
+		require.True(t, limiter.allow("10.0.0.1"))
+		require.False(t, limiter.allow("10.0.0.1"))
+		require.True(t, limiter.allow("10.0.0.2"))
+
+		now = now.Add(time.Second)
+		require.True(t, limiter.allow("10.0.0.1"))
+		require.False(t, limiter.allow("10.0.0.1"))
+	})
+
+	t.Run("Idle clients are forgotten", func(t *testing.T) {
+		now = now.Add(clientIdleTimeout + time.Second)
+		require.True(t, limiter.allow("10.0.0.3"))
+		require.Len(t, limiter.clients, 1)
+	})
+}
@@ -2,71 +2,177 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	log "github.com/sirupsen/logrus"
-
+	"stockticker/internal/chart"
 	"stockticker/internal/controller"
+	"stockticker/internal/logging"
+	"stockticker/internal/monitoring"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	stockCtrler *controller.StockController
-	httpServer  *http.Server
+	stockCtrler      *controller.StockController
+	watchlistCtrler  *controller.WatchlistController
+	alertCtrler      *controller.AlertController
+	portfolioCtrler  *controller.PortfolioController
+	comparisonCtrler *controller.ComparisonController
+	quotePoller      *controller.QuotePoller
+	httpServer       *http.Server
+	listener         net.Listener
+	// Receives the error if serving fails and is closed once serving stops
+	errs chan error
+	// Limits the number of concurrent quote streams
+	streams chan struct{}
+	// Limits how often each client can search for symbols
+	searchLimiter *clientLimiter
+	// Required to change watchlists and alert rules
+	requireToken gin.HandlerFunc
+	// Closed when the server begins shutting down so long lived streams end
+	shuttingDown chan struct{}
+	// Serve Prometheus metrics on /metrics
+	serveMetrics bool
+}
+
+// Config holds the controllers the server serves and the settings it serves them with
+type Config struct {
+	StockCtrler      *controller.StockController
+	WatchlistCtrler  *controller.WatchlistController
+	AlertCtrler      *controller.AlertController
+	PortfolioCtrler  *controller.PortfolioController
+	ComparisonCtrler *controller.ComparisonController
+	QuotePoller      *controller.QuotePoller
+
+	Host string
+	Port int
+	// Concurrent quote streams, including WebSockets
+	MaxStreams int
+	// Serve Prometheus metrics on /metrics
+	ServeMetrics bool
+	// The bearer token required to change watchlists and alert rules. Changes are disabled if it's empty
+	APIToken string
 }
 
-func NewServer(stockCtrler *controller.StockController, ip string, port int) (*Server, error) {
-	return &Server{
-		stockCtrler: stockCtrler,
+func NewServer(cfg Config) (*Server, error) {
+	if cfg.MaxStreams <= 0 {
+		return nil, fmt.Errorf("max streams must be greater than zero")
+	}
+
+	s := &Server{
+		stockCtrler:      cfg.StockCtrler,
+		watchlistCtrler:  cfg.WatchlistCtrler,
+		alertCtrler:      cfg.AlertCtrler,
+		portfolioCtrler:  cfg.PortfolioCtrler,
+		comparisonCtrler: cfg.ComparisonCtrler,
+		quotePoller:      cfg.QuotePoller,
+		streams:          make(chan struct{}, cfg.MaxStreams),
+		searchLimiter:    newClientLimiter(searchRequestsPerMinute, searchBurst),
+		requireToken:     requireToken(cfg.APIToken),
+		shuttingDown:     make(chan struct{}),
+		errs:             make(chan error, 1),
+		serveMetrics:     cfg.ServeMetrics,
 
 		// https://blog.cloudflare.com/the-complete-guide-to-golang-net-http-timeouts/
 		httpServer: &http.Server{
-			Addr:         fmt.Sprintf("%s:%d", ip, port),
+			Addr:         net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
 			ReadTimeout:  10 * time.Second,
 			WriteTimeout: 10 * time.Second,
 		},
-	}, nil
+	}
+	s.httpServer.RegisterOnShutdown(func() {
+		close(s.shuttingDown)
+	})
+	return s, nil
 }
 
-func (s *Server) Start(ctx context.Context) {
-	s.httpServer.Handler = s.setupRouter(false)
+// Start binds the server's address and then serves requests in the background until Stop is called. Port 0 binds any
+// free port, which Addr returns
+func (s *Server) Start() error {
+	s.httpServer.Handler = s.setupRouter()
+
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
 
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Could not start server: %v\n", err)
+		defer close(s.errs)
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.errs <- err
 		}
 	}()
+	return nil
 }
 
-func (s *Server) Stop(ctx context.Context, maxReqDuration time.Duration) error {
-	ctx, cancel := context.WithTimeout(ctx, maxReqDuration)
-	defer cancel()
-	if err := s.httpServer.Shutdown(ctx); err != nil {
-		return err
+// Addr returns the address the server is bound to, or nil if it hasn't been started
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
 	}
-	return nil
+	return s.listener.Addr()
 }
 
-func (s *Server) setupRouter(withLoggingAndMiddlware bool) *gin.Engine {
+// Errors receives the error if serving fails after Start. It's closed once the server stops
+func (s *Server) Errors() <-chan error {
+	return s.errs
+}
+
+// Stop shuts the server down, ending quote streams and waiting for other in-flight requests to complete until ctx is
+// done
+func (s *Server) Stop(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+func (s *Server) setupRouter() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
 
-	var router *gin.Engine
-	if withLoggingAndMiddlware {
-		router = gin.Default()
-	} else {
-		router = gin.New()
-	}
+	router := gin.New()
+	router.Use(requestID(), tracing(), requestMetrics(), accessLog())
 
 	router.LoadHTMLGlob("templates/*")
 
 	router.GET("/", s.stock)
+	router.GET("/watchlists/:name", s.watchlistView)
+	router.GET("/portfolio", s.portfolioView)
+	router.GET("/compare", s.comparisonView)
 
 	api := router.Group("/api")
 	v1 := api.Group("/v1")
 
+	v1.GET("/symbols/search", s.searchLimiter.middleware(), s.searchSymbols)
+	v1.GET("/compare", s.comparison)
+	v1.GET("/stocks/:symbol/daily", s.dailyPrices)
+	v1.GET("/stocks/:symbol/daily.csv", s.dailyCSV)
+	v1.GET("/stocks/:symbol/quote", s.quote)
+	v1.GET("/stocks/:symbol/overview", s.overview)
+	v1.GET("/stocks/:symbol/sparkline.png", s.sparkline)
+	v1.GET("/stream/quotes", s.streamQuotes)
+	v1.GET("/ws/quotes", s.websocketQuotes)
+
+	v1.GET("/watchlists", s.watchlists)
+	v1.GET("/watchlists/:name", s.watchlist)
+	v1.PUT("/watchlists/:name", s.requireToken, s.saveWatchlist)
+	v1.DELETE("/watchlists/:name", s.requireToken, s.deleteWatchlist)
+	v1.GET("/watchlists/:name/summary", s.watchlistSummary)
+
+	v1.GET("/alerts/rules", s.alertRules)
+	v1.PUT("/alerts/rules/:id", s.requireToken, s.saveAlertRule)
+	v1.DELETE("/alerts/rules/:id", s.requireToken, s.deleteAlertRule)
+
+	v1.GET("/portfolio", s.portfolio)
+
+	if s.serveMetrics {
+		router.GET("/metrics", gin.WrapH(monitoring.MetricsHandler()))
+	}
+
 	v1.GET("/liveness", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
@@ -79,13 +185,91 @@ func (s *Server) setupRouter(withLoggingAndMiddlware bool) *gin.Engine {
 }
 
 func (s *Server) stock(c *gin.Context) {
-	viewData, err := s.stockCtrler.Stock(c.Request.Context())
+	movingAverages, err := movingAverages(c)
 	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	viewData, err := s.stockCtrler.Stock(c.Request.Context(), priceOptions(c), movingAverages)
+	if err != nil {
+		if status := errorStatus(err); status != http.StatusInternalServerError {
+			c.String(status, err.Error())
+			return
+		}
 		// TODO: This might be better as a metric if this service will see a high request volume
-		log.Errorf("Failed to retrieve stock data: %v", err)
+		logging.FromContext(c.Request.Context()).Errorf("Failed to retrieve stock data: %v", err)
 		// TODO: Who are the users of this service? Is it safe and/or useful (e.g. rate limiting) to expose more detail to them?
 		c.HTML(http.StatusInternalServerError, "500.tmpl", gin.H{})
 		return
 	}
 	c.HTML(http.StatusOK, s.stockCtrler.ViewTemplate(), viewData)
 }
+
+func (s *Server) searchSymbols(c *gin.Context) {
+	result, err := s.stockCtrler.SearchSymbols(c.Request.Context(), c.Query("q"))
+	if err != nil {
+		jsonError(c, err, "search symbols")
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) dailyPrices(c *gin.Context) {
+	if c.NegotiateFormat(gin.MIMEJSON, mimeCSV) == mimeCSV {
+		s.writeDailyCSV(c, false)
+		return
+	}
+
+	prices, err := s.stockCtrler.DailyPrices(c.Request.Context(), c.Param("symbol"), priceOptions(c))
+	if err != nil {
+		jsonError(c, err, "retrieve daily prices")
+		return
+	}
+	c.JSON(http.StatusOK, prices)
+}
+
+// priceOptions reads the currency and adjusted query parameters
+func priceOptions(c *gin.Context) controller.PriceOptions {
+	// Anything other than a recognised true value leaves prices unadjusted
+	adjusted, _ := strconv.ParseBool(c.Query("adjusted"))
+	return controller.PriceOptions{
+		Currency: c.Query("currency"),
+		Adjusted: adjusted,
+	}
+}
+
+// movingAverages reads the comma separated numbers of days in the ma query parameter
+func movingAverages(c *gin.Context) ([]int, error) {
+	movingAverages := []int{}
+	for _, days := range strings.Split(c.Query("ma"), ",") {
+		days = strings.TrimSpace(days)
+		if days == "" {
+			continue
+		}
+		d, err := strconv.Atoi(days)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s' isn't a number of days", chart.ErrInvalidMovingAverage, days)
+		}
+		movingAverages = append(movingAverages, d)
+	}
+	return movingAverages, nil
+}
+
+func (s *Server) quote(c *gin.Context) {
+	quote, err := s.stockCtrler.Quote(c.Request.Context(), c.Param("symbol"))
+	if err != nil {
+		jsonError(c, err, "retrieve quote")
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
+
+func (s *Server) overview(c *gin.Context) {
+	overview, err := s.stockCtrler.Overview(c.Request.Context(), c.Param("symbol"))
+	if err != nil {
+		jsonError(c, err, "retrieve overview")
+		return
+	}
+	c.JSON(http.StatusOK, overview)
+}
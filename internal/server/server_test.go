@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"stockticker/internal/cache"
+	"stockticker/internal/controller"
+	"stockticker/internal/stockclient"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	gin.SetMode(gin.ReleaseMode)
+	// Templates are loaded relative to the repository root
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestServer(t *testing.T, port int, serveMetrics bool) *Server {
+	server, err := NewServer(Config{Host: "127.0.0.1", Port: port, MaxStreams: 1, ServeMetrics: serveMetrics})
+	require.NoError(t, err)
+	return server
+}
+
+func get(t *testing.T, url string) int {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestStart(t *testing.T) {
+	t.Run("Serves on any free port until stopped", func(t *testing.T) {
+		server := newTestServer(t, 0, true)
+		require.Nil(t, server.Addr())
+		require.NoError(t, server.Start())
+		addr := server.Addr().(*net.TCPAddr)
+		require.NotZero(t, addr.Port)
+
+		require.Equal(t, http.StatusOK, get(t, "http://"+addr.String()+"/api/v1/liveness"))
+		require.Equal(t, http.StatusOK, get(t, "http://"+addr.String()+"/metrics"))
+
+		require.NoError(t, server.Stop(context.Background()))
+		err, ok := <-server.Errors()
+		require.False(t, ok)
+		require.NoError(t, err)
+		_, err = http.Get("http://" + addr.String() + "/api/v1/liveness")
+		require.Error(t, err)
+	})
+
+	t.Run("Metrics are only served on request", func(t *testing.T) {
+		server := newTestServer(t, 0, false)
+		require.NoError(t, server.Start())
+		defer func() {
+			require.NoError(t, server.Stop(context.Background()))
+		}()
+		require.Equal(t, http.StatusNotFound, get(t, "http://"+server.Addr().String()+"/metrics"))
+	})
+
+	t.Run("Bind errors are returned", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		server := newTestServer(t, listener.Addr().(*net.TCPAddr).Port, false)
+		require.ErrorContains(t, server.Start(), "address already in use")
+		require.Nil(t, server.Addr())
+	})
+}
+
+// quoteClient only serves quotes, at a fixed price
+type quoteClient struct{}
+
+var errNotQuote = errors.New("only quotes are supported")
+
+func (quoteClient) Stock(context.Context, string, stockclient.Order) (*stockclient.Stock, error) {
+	return nil, errNotQuote
+}
+
+func (quoteClient) AdjustedStock(context.Context, string, stockclient.Order) (*stockclient.Stock, error) {
+	return nil, errNotQuote
+}
+
+func (quoteClient) SearchSymbols(context.Context, string) ([]*stockclient.SymbolMatch, error) {
+	return nil, errNotQuote
+}
+
+func (quoteClient) Quote(_ context.Context, symbol string) (*stockclient.Quote, error) {
+	return &stockclient.Quote{Symbol: symbol, Price: 100, Timestamp: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, nil
+}
+
+func (quoteClient) Overview(context.Context, string) (*stockclient.Overview, error) {
+	return nil, errNotQuote
+}
+
+// newStreamingTestServer serves quote streams backed by a running quote poller
+func newStreamingTestServer(t *testing.T, maxStreams, maxSymbols int) *httptest.Server {
+	cacheClient, _ := cache.NewNullClient("", 0)
+	stockCtrler, err := controller.NewStockController(quoteClient{}, cacheClient, "MSFT", 2)
+	require.NoError(t, err)
+	poller, err := controller.NewQuotePoller(stockCtrler, time.Hour, maxSymbols)
+	require.NoError(t, err)
+	server, err := NewServer(Config{StockCtrler: stockCtrler, QuotePoller: poller, Host: "127.0.0.1", MaxStreams: maxStreams})
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server.setupRouter())
+	t.Cleanup(httpServer.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go poller.Run(ctx)
+	return httpServer
+}
+
+func TestStreamQuotes(t *testing.T) {
+	httpServer := newStreamingTestServer(t, 1, 0)
+	url := httpServer.URL + "/api/v1/stream/quotes?symbols=msft"
+
+	t.Run("Symbols without quotes are rejected", func(t *testing.T) {
+		resp, err := http.Get(httpServer.URL + "/api/v1/stream/quotes?symbols=msft,crypto:btc-usd")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// Events are separated by blank lines. The first only sets the retry interval
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() map[string]string {
+		event := map[string]string{}
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return event
+			}
+			field, value, _ := strings.Cut(line, ": ")
+			event[field] = value
+		}
+	}
+	require.Equal(t, map[string]string{"retry": "5000"}, readEvent())
+
+	event := readEvent()
+	require.Equal(t, "quote", event["event"])
+	require.Equal(t, "1", event["id"])
+	quote := stockclient.Quote{}
+	require.NoError(t, json.Unmarshal([]byte(event["data"]), &quote))
+	require.Equal(t, "MSFT", quote.Symbol)
+	require.Equal(t, 100.0, quote.Price)
+
+	t.Run("Streams past the maximum are rejected", func(t *testing.T) {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Equal(t, "5", resp.Header.Get("Retry-After"))
+	})
+}
+
+func TestWebsocketQuotes(t *testing.T) {
+	httpServer := newStreamingTestServer(t, 1, 2)
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/api/v1/ws/quotes"
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	// Quotes are published in the background so may arrive before or after a reply. next skips other messages
+	next := func(typ string) wsResponse {
+		for {
+			resp := wsResponse{}
+			require.NoError(t, conn.ReadJSON(&resp))
+			if resp.Type == typ {
+				return resp
+			}
+		}
+	}
+
+	require.NoError(t, conn.WriteJSON(wsRequest{Action: "subscribe", Symbols: []string{"msft"}}))
+	resp := next("subscriptions")
+	require.Equal(t, []string{"MSFT"}, resp.Symbols)
+	resp = next("quote")
+	require.Equal(t, uint64(1), resp.ID)
+	require.Equal(t, "MSFT", resp.Quote.Symbol)
+	require.Equal(t, 100.0, resp.Quote.Price)
+
+	t.Run("Symbols past the maximum are rejected", func(t *testing.T) {
+		require.NoError(t, conn.WriteJSON(wsRequest{Action: "subscribe", Symbols: []string{"AAPL", "GOOG"}}))
+		resp := next("error")
+		require.Contains(t, resp.Error, "at most 2 symbols")
+		require.ElementsMatch(t, []string{"AAPL", "MSFT"}, resp.Symbols)
+	})
+
+	t.Run("Symbols without quotes are rejected", func(t *testing.T) {
+		require.NoError(t, conn.WriteJSON(wsRequest{Action: "subscribe", Symbols: []string{"fx:EUR-USD"}}))
+		resp := next("error")
+		require.Contains(t, resp.Error, "quotes are not supported for this asset class 'fx'")
+	})
+
+	t.Run("Connections past the maximum streams are rejected", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+		require.ErrorIs(t, err, websocket.ErrBadHandshake)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
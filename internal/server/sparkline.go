@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stockticker/internal/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSparklineDays   = 30
+	defaultSparklineWidth  = 120
+	defaultSparklineHeight = 30
+)
+
+func (s *Server) sparkline(c *gin.Context) {
+	days, err := intQuery(c, "days", defaultSparklineDays)
+	if err != nil {
+		jsonError(c, err, "render sparkline")
+		return
+	}
+	width, err := intQuery(c, "w", defaultSparklineWidth)
+	if err != nil {
+		jsonError(c, err, "render sparkline")
+		return
+	}
+	height, err := intQuery(c, "h", defaultSparklineHeight)
+	if err != nil {
+		jsonError(c, err, "render sparkline")
+		return
+	}
+
+	sparkline, err := s.stockCtrler.Sparkline(c.Request.Context(), c.Param("symbol"), days, width, height)
+	if err != nil {
+		jsonError(c, err, "render sparkline")
+		return
+	}
+
+	c.Header("ETag", sparkline.ETag)
+	// Embedded images are requested often, so let clients reuse them briefly before revalidating
+	c.Header("Cache-Control", "public, max-age=300")
+	if etagMatches(c.GetHeader("If-None-Match"), sparkline.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "image/png", sparkline.PNG)
+}
+
+// intQuery reads an integer query parameter, returning def if it isn't set
+func intQuery(c *gin.Context, key string, def int) (int, error) {
+	val := c.Query(key)
+	if val == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be a number", controller.ErrInvalidSparkline, key)
+	}
+	return i, nil
+}
+
+// etagMatches reports whether an If-None-Match header matches etag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"stockticker/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Keeps idle streams from being closed by proxies and load balancers
+	sseHeartbeatInterval = 15 * time.Second
+	// How long clients should wait before reconnecting
+	sseRetryInterval = 5 * time.Second
+	// Applied to each write as the server's write timeout is intended for regular requests
+	sseWriteTimeout = 10 * time.Second
+	// Must be at least the maximum number of symbols per subscription so replays don't overflow the buffer
+	subscriptionBufferSize = 128
+)
+
+// streamQuotes streams quote updates for the comma separated symbols in the symbols query parameter as Server-Sent
+// Events. Reconnecting clients that send Last-Event-ID receive the updates they missed where possible
+func (s *Server) streamQuotes(c *gin.Context) {
+	select {
+	case s.streams <- struct{}{}:
+		defer func() { <-s.streams }()
+	default:
+		c.Header("Retry-After", strconv.Itoa(int(sseRetryInterval.Seconds())))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "too many concurrent streams"})
+		return
+	}
+
+	symbols := splitSymbols(c.Query("symbols"))
+	if len(symbols) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one symbol is required"})
+		return
+	}
+
+	// An unparseable ID is treated the same as a new client
+	lastID, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	sub, err := s.quotePoller.Subscribe(c.Request.Context(), symbols, lastID, subscriptionBufferSize)
+	if err != nil {
+		jsonError(c, err, "subscribe to quotes")
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Disables response buffering in nginx based ingresses
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	write := func(format string, args ...any) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+		if _, err := fmt.Fprintf(c.Writer, format, args...); err != nil {
+			logging.FromContext(c.Request.Context()).Debugf("Failed to write to stream: %v", err)
+			return false
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	if !write("retry: %d\n\n", sseRetryInterval.Milliseconds()) {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-s.shuttingDown:
+			return
+		case update, ok := <-sub.Updates():
+			if !ok {
+				// Either the poller stopped or the client fell behind. Either way the client will reconnect
+				return
+			}
+			data, err := json.Marshal(update.Quote)
+			if err != nil {
+				logging.FromContext(c.Request.Context()).Errorf("Failed to encode quote: %v", err)
+				return
+			}
+			if !write("id: %d\nevent: quote\ndata: %s\n\n", update.ID, data) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": heartbeat\n\n") {
+				return
+			}
+		}
+	}
+}
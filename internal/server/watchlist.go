@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+
+	"stockticker/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+type saveWatchlistRequest struct {
+	Symbols []string `json:"symbols" binding:"required"`
+}
+
+func (s *Server) watchlistView(c *gin.Context) {
+	viewData, err := s.watchlistCtrler.Summary(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		status := errorStatus(err)
+		if status == http.StatusInternalServerError {
+			logging.FromContext(c.Request.Context()).Errorf("Failed to summarise watchlist: %v", err)
+			c.HTML(status, "500.tmpl", gin.H{})
+			return
+		}
+		c.String(status, err.Error())
+		return
+	}
+	c.HTML(http.StatusOK, s.watchlistCtrler.ViewTemplate(), viewData)
+}
+
+func (s *Server) watchlists(c *gin.Context) {
+	c.JSON(http.StatusOK, s.watchlistCtrler.Watchlists(c.Request.Context()))
+}
+
+func (s *Server) watchlist(c *gin.Context) {
+	watchlist, err := s.watchlistCtrler.Watchlist(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		jsonError(c, err, "retrieve watchlist")
+		return
+	}
+	c.JSON(http.StatusOK, watchlist)
+}
+
+func (s *Server) saveWatchlist(c *gin.Context) {
+	req := saveWatchlistRequest{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	watchlist, err := s.watchlistCtrler.SaveWatchlist(c.Request.Context(), c.Param("name"), req.Symbols)
+	if err != nil {
+		jsonError(c, err, "save watchlist")
+		return
+	}
+	c.JSON(http.StatusOK, watchlist)
+}
+
+func (s *Server) deleteWatchlist(c *gin.Context) {
+	err := s.watchlistCtrler.DeleteWatchlist(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		jsonError(c, err, "delete watchlist")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) watchlistSummary(c *gin.Context) {
+	viewData, err := s.watchlistCtrler.Summary(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		jsonError(c, err, "summarise watchlist")
+		return
+	}
+	c.JSON(http.StatusOK, viewData)
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"stockticker/internal/controller"
+	"stockticker/internal/logging"
+	"stockticker/internal/stockclient"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout = 10 * time.Second
+	// Connections are closed if no pong is received within this time
+	wsPongTimeout = 60 * time.Second
+	// Must be less than wsPongTimeout
+	wsPingInterval = (wsPongTimeout * 9) / 10
+	// Client messages are small so anything larger is either a bug or abuse
+	wsMaxMessageSize = 4096
+)
+
+// The default origin check is kept so only pages served from the same host can connect
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// wsRequest is a message sent by a WebSocket client
+type wsRequest struct {
+	// Either subscribe or unsubscribe
+	Action  string   `json:"action"`
+	Symbols []string `json:"symbols"`
+}
+
+// wsResponse is a message sent to a WebSocket client. Type is one of quote, subscriptions or error
+type wsResponse struct {
+	Type    string             `json:"type"`
+	ID      uint64             `json:"id,omitempty"`
+	Quote   *stockclient.Quote `json:"quote,omitempty"`
+	Symbols []string           `json:"symbols,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// websocketQuotes upgrades the connection to a WebSocket that clients can send subscribe and unsubscribe requests
+// over, and that quote updates for subscribed symbols are sent over
+func (s *Server) websocketQuotes(c *gin.Context) {
+	select {
+	case s.streams <- struct{}{}:
+		defer func() { <-s.streams }()
+	default:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "too many concurrent streams"})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already responded to the client
+		logging.FromContext(c.Request.Context()).Debugf("Failed to upgrade to WebSocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sub, err := s.quotePoller.Subscribe(c.Request.Context(), nil, 0, subscriptionBufferSize)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Errorf("Failed to subscribe to quotes: %v", err)
+		return
+	}
+	defer sub.Close()
+
+	// Only one goroutine may write to a connection at a time so replies are passed to the writing goroutine
+	replies := make(chan wsResponse)
+	readDone := make(chan struct{})
+	writeDone := make(chan struct{})
+	defer close(writeDone)
+	go func() {
+		defer close(readDone)
+		readWebsocket(c.Request.Context(), conn, sub, replies, writeDone)
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-readDone:
+			return
+		case <-s.shuttingDown:
+			writeClose(conn, websocket.CloseGoingAway, "server shutting down")
+			return
+		case reply := <-replies:
+			err = writeJSON(conn, reply)
+		case update, ok := <-sub.Updates():
+			if !ok {
+				writeClose(conn, websocket.CloseTryAgainLater, "not keeping up with updates")
+				return
+			}
+			err = writeJSON(conn, wsResponse{Type: "quote", ID: update.ID, Quote: update.Quote})
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		}
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Debugf("Failed to write to WebSocket: %v", err)
+			return
+		}
+	}
+}
+
+// readWebsocket handles client requests until the connection fails or writeDone is closed
+func readWebsocket(ctx context.Context, conn *websocket.Conn, sub *controller.Subscription, replies chan<- wsResponse, writeDone <-chan struct{}) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		req := wsRequest{}
+		if err := conn.ReadJSON(&req); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				logging.FromContext(ctx).Debugf("Failed to read from WebSocket: %v", err)
+			}
+			return
+		}
+
+		reply := handleWebsocketRequest(sub, &req)
+		select {
+		case replies <- reply:
+		case <-writeDone:
+			return
+		}
+	}
+}
+
+func handleWebsocketRequest(sub *controller.Subscription, req *wsRequest) wsResponse {
+	switch req.Action {
+	case "subscribe":
+		for _, symbol := range req.Symbols {
+			if err := sub.Add(symbol); err != nil {
+				return wsResponse{Type: "error", Error: err.Error(), Symbols: sub.Symbols()}
+			}
+		}
+	case "unsubscribe":
+		for _, symbol := range req.Symbols {
+			sub.Remove(symbol)
+		}
+	default:
+		return wsResponse{Type: "error", Error: "action must be subscribe or unsubscribe"}
+	}
+	return wsResponse{Type: "subscriptions", Symbols: sub.Symbols()}
+}
+
+func writeJSON(conn *websocket.Conn, resp wsResponse) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(resp)
+}
+
+func writeClose(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+}
@@ -3,20 +3,36 @@ package stockclient
 // Code adapted from https://github.com/sklinkert/alphavantage
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"slices"
+	"stockticker/internal/logging"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	BaseURL = "https://www.alphavantage.co"
+
+	tracer = otel.Tracer("stockticker/internal/stockclient")
 )
 
 type TimeSeriesData struct {
 	Close float64 `json:"4. close,string"`
+	// Not included for FX pairs
+	Volume float64 `json:"5. volume,string"`
 }
 
 // TimeSeries represents the overall struct for time series
@@ -26,14 +42,90 @@ type TimeSeries struct {
 
 type ErrorResponse struct {
 	ErrorMessage *string `json:"Error Message"`
+	// Rate limits are reported in one of these with a 200 status
+	Note        *string `json:"Note"`
+	Information *string `json:"Information"`
+}
+
+type symbolSearchMatch struct {
+	Symbol     string  `json:"1. symbol"`
+	Name       string  `json:"2. name"`
+	Type       string  `json:"3. type"`
+	Region     string  `json:"4. region"`
+	Currency   string  `json:"8. currency"`
+	MatchScore float64 `json:"9. matchScore,string"`
+}
+
+// SymbolSearch represents the response from the SYMBOL_SEARCH endpoint
+type SymbolSearch struct {
+	BestMatches []symbolSearchMatch `json:"bestMatches"`
+}
+
+type globalQuoteData struct {
+	Symbol           string `json:"01. symbol"`
+	Price            string `json:"05. price"`
+	Volume           string `json:"06. volume"`
+	LatestTradingDay string `json:"07. latest trading day"`
+	PreviousClose    string `json:"08. previous close"`
+	Change           string `json:"09. change"`
+	ChangePercent    string `json:"10. change percent"`
+}
+
+// GlobalQuote represents the response from the GLOBAL_QUOTE endpoint
+type GlobalQuote struct {
+	GlobalQuote globalQuoteData `json:"Global Quote"`
+}
+
+type adjustedTimeSeriesData struct {
+	Close            float64 `json:"4. close,string"`
+	AdjustedClose    float64 `json:"5. adjusted close,string"`
+	Volume           float64 `json:"6. volume,string"`
+	Dividend         float64 `json:"7. dividend amount,string"`
+	SplitCoefficient float64 `json:"8. split coefficient,string"`
+}
+
+// AdjustedTimeSeries represents the response from the TIME_SERIES_DAILY_ADJUSTED endpoint
+type AdjustedTimeSeries struct {
+	TimeSeriesDaily map[string]adjustedTimeSeriesData `json:"Time Series (Daily)"`
+}
+
+// companyOverview represents the response from the OVERVIEW endpoint. Unknown values are "None" or "-"
+type companyOverview struct {
+	Symbol               string `json:"Symbol"`
+	Name                 string `json:"Name"`
+	Exchange             string `json:"Exchange"`
+	Currency             string `json:"Currency"`
+	Sector               string `json:"Sector"`
+	Industry             string `json:"Industry"`
+	MarketCapitalization string `json:"MarketCapitalization"`
+	PERatio              string `json:"PERatio"`
+	WeekHigh52           string `json:"52WeekHigh"`
+	WeekLow52            string `json:"52WeekLow"`
+}
+
+// DigitalCurrencyTimeSeries represents the response from the DIGITAL_CURRENCY_DAILY endpoint
+type DigitalCurrencyTimeSeries struct {
+	TimeSeriesDigitalCurrencyDaily map[string]TimeSeriesData `json:"Time Series (Digital Currency Daily)"`
+}
+
+// FXTimeSeries represents the response from the FX_DAILY endpoint
+type FXTimeSeries struct {
+	TimeSeriesFXDaily map[string]TimeSeriesData `json:"Time Series FX (Daily)"`
 }
 
 type StockClient struct {
-	apiKey     string
+	apiKeys    *keyPool
 	httpClient *http.Client
 }
 
-func NewAlphaVantageClient(apiKey string) (*StockClient, error) {
+// NewAlphaVantageClient creates a client that rotates through apiKeys as each is rate limited. dailyQuota is the
+// number of requests each key can make per day, or 0 if it's unknown, and is only used to report the remaining quota
+func NewAlphaVantageClient(apiKeys []string, dailyQuota int) (*StockClient, error) {
+	pool, err := newKeyPool(apiKeys, dailyQuota)
+	if err != nil {
+		return nil, err
+	}
+
 	httpClient := &http.Client{
 		Timeout: time.Second * 30,
 		Transport: &http.Transport{
@@ -42,19 +134,54 @@ func NewAlphaVantageClient(apiKey string) (*StockClient, error) {
 	}
 
 	return &StockClient{
-		apiKey:     apiKey,
+		apiKeys:    pool,
 		httpClient: httpClient,
 	}, nil
 }
 
-func toStruct(buf []byte) ([]*DayData, error) {
+// SetAPIKeys replaces the API keys used for new requests
+func (c *StockClient) SetAPIKeys(apiKeys []string) error {
+	return c.apiKeys.set(apiKeys)
+}
+
+// KeyMetrics reports the remaining quota and number of rate limited responses for each API key. It's registered once
+// per client with prometheus.MustRegister
+func (c *StockClient) KeyMetrics() prometheus.Collector {
+	return c.apiKeys
+}
+
+// checkErrorResponse returns an error if the response body is a message rather than data. Rate limit messages are
+// handled when the request is made, so any other Note or Information is about something else, e.g. a premium endpoint
+func checkErrorResponse(buf []byte) error {
 	errResp := &ErrorResponse{}
-	err := json.Unmarshal(buf, errResp)
-	if err == nil {
-		if errResp.ErrorMessage != nil {
-			return nil, fmt.Errorf("failed to get stock data: %s", *errResp.ErrorMessage)
+	if err := json.Unmarshal(buf, errResp); err != nil {
+		return nil
+	}
+	for _, msg := range []*string{errResp.ErrorMessage, errResp.Information, errResp.Note} {
+		if msg != nil {
+			return fmt.Errorf("%s", *msg)
 		}
 	}
+	return nil
+}
+
+// isRateLimited reports whether the response body is a rate limit message rather than data
+func isRateLimited(buf []byte) bool {
+	errResp := &ErrorResponse{}
+	if err := json.Unmarshal(buf, errResp); err != nil {
+		return false
+	}
+	if errResp.Note != nil {
+		return true
+	}
+	// Also used for other messages, e.g. about premium endpoints
+	return errResp.Information != nil && strings.Contains(strings.ToLower(*errResp.Information), "rate limit")
+}
+
+func toStruct(buf []byte) ([]*DayData, error) {
+	if err := checkErrorResponse(buf); err != nil {
+		return nil, fmt.Errorf("failed to get stock data: %w", err)
+	}
 
 	// TODO: Is returning no data always an error?
 	timeSeries := &TimeSeries{}
@@ -62,16 +189,21 @@ func toStruct(buf []byte) ([]*DayData, error) {
 		return nil, err
 	}
 
+	return toDailyData(timeSeries.TimeSeriesDaily)
+}
+
+func toDailyData(timeSeries map[string]TimeSeriesData) ([]*DayData, error) {
 	dailyData := []*DayData{}
-	for dateStr, data := range timeSeries.TimeSeriesDaily {
+	for dateStr, data := range timeSeries {
 		date, err := time.Parse(time.DateOnly, dateStr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse date '%s' from response JSON: %w", dateStr, err)
 		}
 
 		dayData := &DayData{
-			Date:  date,
-			Close: data.Close,
+			Date:   date,
+			Close:  data.Close,
+			Volume: data.Volume,
 		}
 		dailyData = append(dailyData, dayData)
 	}
@@ -79,9 +211,38 @@ func toStruct(buf []byte) ([]*DayData, error) {
 	return dailyData, nil
 }
 
-func (c *StockClient) Stock(symbol string, sortOrder Order) (*Stock, error) {
-	url := fmt.Sprintf("%s/query?function=TIME_SERIES_DAILY&symbol=%s&apikey=%s&outputsize=%s", BaseURL, symbol, c.apiKey, "full")
-	body, _, err := c.makeHTTPRequest(url)
+// Stock returns the daily closes for symbol, which may be prefixed with its asset class
+func (c *StockClient) Stock(ctx context.Context, symbol string, sortOrder Order) (*Stock, error) {
+	var dailyData []*DayData
+	var err error
+	switch class, rest := ParseSymbol(symbol); class {
+	case Equity:
+		dailyData, err = c.equityDaily(ctx, rest)
+	case Crypto:
+		dailyData, err = c.cryptoDaily(ctx, rest)
+	case FXPair:
+		dailyData, err = c.fxPairDaily(ctx, rest)
+	default:
+		err = fmt.Errorf("unknown asset class '%s'", class)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Otherwise an empty series would be cached until the next day
+	if len(dailyData) == 0 {
+		return nil, fmt.Errorf("no daily data returned for '%s'", symbol)
+	}
+
+	sort(dailyData, sortOrder)
+
+	return &Stock{
+		DailyData: dailyData,
+	}, nil
+}
+
+func (c *StockClient) equityDaily(ctx context.Context, symbol string) ([]*DayData, error) {
+	query := fmt.Sprintf("function=TIME_SERIES_DAILY&symbol=%s&outputsize=%s", url.QueryEscape(symbol), "full")
+	body, err := c.get(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -118,6 +279,52 @@ func (c *StockClient) Stock(symbol string, sortOrder Order) (*Stock, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
+	return dailyData, nil
+}
+
+func (c *StockClient) AdjustedStock(ctx context.Context, symbol string, sortOrder Order) (*Stock, error) {
+	if class, _ := ParseSymbol(symbol); class != Equity {
+		return nil, fmt.Errorf("adjusted prices are %w '%s'", ErrUnsupportedAssetClass, class)
+	}
+
+	query := fmt.Sprintf("function=TIME_SERIES_DAILY_ADJUSTED&symbol=%s&outputsize=%s", url.QueryEscape(symbol), "full")
+	body, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkErrorResponse(body); err != nil {
+		return nil, fmt.Errorf("failed to get adjusted stock data: %w", err)
+	}
+
+	timeSeries := &AdjustedTimeSeries{}
+	if err := json.Unmarshal(body, timeSeries); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	dailyData := []*DayData{}
+	for dateStr, data := range timeSeries.TimeSeriesDaily {
+		date, err := time.Parse(time.DateOnly, dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date '%s' from response JSON: %w", dateStr, err)
+		}
+
+		dayData := &DayData{
+			Date:          date,
+			Close:         data.Close,
+			AdjustedClose: data.AdjustedClose,
+			Dividend:      data.Dividend,
+			Volume:        data.Volume,
+		}
+		// The coefficient is 1 on days without a split
+		if data.SplitCoefficient != 1 {
+			dayData.SplitCoefficient = data.SplitCoefficient
+		}
+		dailyData = append(dailyData, dayData)
+	}
+	if len(dailyData) == 0 {
+		return nil, fmt.Errorf("no adjusted daily data returned for '%s'", symbol)
+	}
 
 	sort(dailyData, sortOrder)
 
@@ -126,6 +333,240 @@ func (c *StockClient) Stock(symbol string, sortOrder Order) (*Stock, error) {
 	}, nil
 }
 
+func (c *StockClient) cryptoDaily(ctx context.Context, pair string) ([]*DayData, error) {
+	currency, market, ok := SplitPair(pair)
+	if !ok {
+		return nil, fmt.Errorf("invalid crypto pair '%s'", pair)
+	}
+
+	query := fmt.Sprintf("function=DIGITAL_CURRENCY_DAILY&symbol=%s&market=%s", url.QueryEscape(currency), url.QueryEscape(market))
+	body, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkErrorResponse(body); err != nil {
+		return nil, fmt.Errorf("failed to get crypto data: %w", err)
+	}
+
+	timeSeries := &DigitalCurrencyTimeSeries{}
+	if err := json.Unmarshal(body, timeSeries); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return toDailyData(timeSeries.TimeSeriesDigitalCurrencyDaily)
+}
+
+// fxPairDaily returns the daily closing rates of an FX pair as prices
+func (c *StockClient) fxPairDaily(ctx context.Context, pair string) ([]*DayData, error) {
+	from, to, ok := SplitPair(pair)
+	if !ok {
+		return nil, fmt.Errorf("invalid fx pair '%s'", pair)
+	}
+
+	rates, err := c.FXDaily(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	dailyData := make([]*DayData, 0, len(rates))
+	for _, rate := range rates {
+		dailyData = append(dailyData, &DayData{Date: rate.Date, Close: rate.Rate})
+	}
+	return dailyData, nil
+}
+
+func (c *StockClient) SearchSymbols(ctx context.Context, keywords string) ([]*SymbolMatch, error) {
+	query := fmt.Sprintf("function=SYMBOL_SEARCH&keywords=%s", url.QueryEscape(keywords))
+	body, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkErrorResponse(body); err != nil {
+		return nil, fmt.Errorf("failed to search symbols: %w", err)
+	}
+
+	search := &SymbolSearch{}
+	if err := json.Unmarshal(body, search); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	matches := make([]*SymbolMatch, 0, len(search.BestMatches))
+	for _, m := range search.BestMatches {
+		matches = append(matches, &SymbolMatch{
+			Symbol:     m.Symbol,
+			Name:       m.Name,
+			Type:       m.Type,
+			Region:     m.Region,
+			Currency:   m.Currency,
+			MatchScore: m.MatchScore,
+		})
+	}
+	return matches, nil
+}
+
+func (c *StockClient) Quote(ctx context.Context, symbol string) (*Quote, error) {
+	if class, _ := ParseSymbol(symbol); class != Equity {
+		return nil, fmt.Errorf("quotes are %w '%s'", ErrUnsupportedAssetClass, class)
+	}
+
+	query := fmt.Sprintf("function=GLOBAL_QUOTE&symbol=%s", url.QueryEscape(symbol))
+	body, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkErrorResponse(body); err != nil {
+		return nil, fmt.Errorf("failed to get quote: %w", err)
+	}
+
+	globalQuote := &GlobalQuote{}
+	if err := json.Unmarshal(body, globalQuote); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	quote, err := toQuote(&globalQuote.GlobalQuote)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return quote, nil
+}
+
+func toQuote(data *globalQuoteData) (*Quote, error) {
+	// Unknown symbols result in an empty quote rather than an error message
+	if data.Symbol == "" {
+		return nil, fmt.Errorf("no quote returned")
+	}
+
+	var err error
+	quote := &Quote{Symbol: data.Symbol}
+	parseFloat := func(name, val string) float64 {
+		if err != nil {
+			return 0
+		}
+		var f float64
+		f, err = strconv.ParseFloat(val, 64)
+		if err != nil {
+			err = fmt.Errorf("failed to parse %s '%s': %w", name, val, err)
+		}
+		return f
+	}
+	quote.Price = parseFloat("price", data.Price)
+	quote.PreviousClose = parseFloat("previous close", data.PreviousClose)
+	quote.Change = parseFloat("change", data.Change)
+	quote.ChangePercent = parseFloat("change percent", strings.TrimSuffix(data.ChangePercent, "%"))
+	if err != nil {
+		return nil, err
+	}
+
+	quote.Volume, err = strconv.ParseInt(data.Volume, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse volume '%s': %w", data.Volume, err)
+	}
+
+	quote.Timestamp, err = time.Parse(time.DateOnly, data.LatestTradingDay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse date '%s': %w", data.LatestTradingDay, err)
+	}
+
+	return quote, nil
+}
+
+func (c *StockClient) FXDaily(ctx context.Context, fromCurrency, toCurrency string) ([]*FXRate, error) {
+	query := fmt.Sprintf("function=FX_DAILY&from_symbol=%s&to_symbol=%s&outputsize=%s",
+		url.QueryEscape(fromCurrency), url.QueryEscape(toCurrency), "full")
+	body, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkErrorResponse(body); err != nil {
+		return nil, fmt.Errorf("failed to get fx rates: %w", err)
+	}
+
+	timeSeries := &FXTimeSeries{}
+	if err := json.Unmarshal(body, timeSeries); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(timeSeries.TimeSeriesFXDaily) == 0 {
+		return nil, fmt.Errorf("no fx rates returned for %s to %s", fromCurrency, toCurrency)
+	}
+
+	rates := make([]*FXRate, 0, len(timeSeries.TimeSeriesFXDaily))
+	for dateStr, data := range timeSeries.TimeSeriesFXDaily {
+		date, err := time.Parse(time.DateOnly, dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse date '%s' from response JSON: %w", dateStr, err)
+		}
+		rates = append(rates, &FXRate{Date: date, Rate: data.Close})
+	}
+	slices.SortFunc(rates, func(a, b *FXRate) int {
+		return b.Date.Compare(a.Date)
+	})
+	return rates, nil
+}
+
+func (c *StockClient) Overview(ctx context.Context, symbol string) (*Overview, error) {
+	if class, _ := ParseSymbol(symbol); class != Equity {
+		return nil, fmt.Errorf("overviews are %w '%s'", ErrUnsupportedAssetClass, class)
+	}
+
+	query := fmt.Sprintf("function=OVERVIEW&symbol=%s", url.QueryEscape(symbol))
+	body, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkErrorResponse(body); err != nil {
+		return nil, fmt.Errorf("failed to get overview: %w", err)
+	}
+
+	data := &companyOverview{}
+	if err := json.Unmarshal(body, data); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	// Unknown symbols result in an empty object rather than an error message
+	if data.Symbol == "" {
+		return nil, fmt.Errorf("no overview returned")
+	}
+
+	overview := &Overview{
+		Symbol:   data.Symbol,
+		Name:     data.Name,
+		Exchange: data.Exchange,
+		Currency: data.Currency,
+		Sector:   data.Sector,
+		Industry: data.Industry,
+	}
+	if overview.MarketCap, err = parseOptional(data.MarketCapitalization, func(s string) (int64, error) {
+		return strconv.ParseInt(s, 10, 64)
+	}); err != nil {
+		return nil, fmt.Errorf("failed to parse market capitalization: %w", err)
+	}
+	parseFloat := func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	}
+	if overview.PERatio, err = parseOptional(data.PERatio, parseFloat); err != nil {
+		return nil, fmt.Errorf("failed to parse P/E ratio: %w", err)
+	}
+	if overview.WeekHigh52, err = parseOptional(data.WeekHigh52, parseFloat); err != nil {
+		return nil, fmt.Errorf("failed to parse 52 week high: %w", err)
+	}
+	if overview.WeekLow52, err = parseOptional(data.WeekLow52, parseFloat); err != nil {
+		return nil, fmt.Errorf("failed to parse 52 week low: %w", err)
+	}
+	return overview, nil
+}
+
+// parseOptional parses val, returning the zero value if it's one of the placeholders used for unknown values
+func parseOptional[T any](val string, parse func(string) (T, error)) (T, error) {
+	var zero T
+	switch val {
+	case "", "None", "-":
+		return zero, nil
+	}
+	return parse(val)
+}
+
 func sort(dailyData []*DayData, sortOrder Order) {
 	if sortOrder == Ascending {
 		slices.SortFunc(dailyData, func(a, b *DayData) int {
@@ -150,17 +591,59 @@ func sort(dailyData []*DayData, sortOrder Order) {
 	}
 }
 
-func (c *StockClient) makeHTTPRequest(url string) ([]byte, int, error) {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// get requests query, which mustn't include the API key. Rate limited requests are retried with each of the other
+// keys in turn
+func (c *StockClient) get(ctx context.Context, query string) ([]byte, error) {
+	for range c.apiKeys.len() {
+		key := c.apiKeys.get()
+		body, status, err := c.makeHTTPRequest(ctx, fmt.Sprintf("%s/query?%s&apikey=%s", BaseURL, query, url.QueryEscape(key)))
+		if err != nil && status != http.StatusTooManyRequests {
+			return nil, err
+		}
+		if err == nil && !isRateLimited(body) {
+			c.apiKeys.record(key, false)
+			return body, nil
+		}
+		logging.FromContext(ctx).Warn("API key rate limited, switching to the next key")
+		c.apiKeys.record(key, true)
+		c.apiKeys.rotate(key)
+	}
+	return nil, ErrRateLimited
+}
+
+func (c *StockClient) makeHTTPRequest(ctx context.Context, reqURL string) (_ []byte, _ int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
 	if err != nil {
-		return nil, http.StatusInternalServerError, fmt.Errorf("building http request failed: %w", err)
+		return nil, http.StatusInternalServerError, fmt.Errorf("building http request failed: %w", withoutURL(err))
+	}
+
+	// The URL isn't recorded as it includes the API key
+	query := req.URL.Query()
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Hostname()),
 	}
+	if symbol := query.Get("symbol"); symbol != "" {
+		attrs = append(attrs, attribute.String("symbol", symbol))
+	}
+	ctx, span := tracer.Start(ctx, "AlphaVantage "+query.Get("function"),
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	// Trace context isn't propagated as Alpha Vantage is a third party outside the trace
+	req = req.WithContext(ctx)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, http.StatusInternalServerError, fmt.Errorf("http request failed: %w", err)
+		return nil, http.StatusInternalServerError, fmt.Errorf("http request failed: %w", withoutURL(err))
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -173,3 +656,12 @@ func (c *StockClient) makeHTTPRequest(url string) ([]byte, int, error) {
 
 	return body, resp.StatusCode, nil
 }
+
+// withoutURL removes the URL, which includes the API key, from errors about a request so they're safe to log
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -1,15 +1,26 @@
 package stockclient
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestStock(t *testing.T) {
+	ctx := context.Background()
 	successResp := `{
 		"Meta Data": {
 			"1. Information": "Weekly Prices (open, high, low, close) and Volumes",
@@ -65,16 +76,17 @@ func TestStock(t *testing.T) {
 	t.Run("Client request with a success response", func(t *testing.T) {
 		resp = successResp
 		BaseURL = server.URL
-		client, err := NewAlphaVantageClient("DUMMY_API_KEY")
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
 		require.NoError(t, err)
 
-		stock, err := client.Stock("DUMMY_SYMBOL", Ascending)
+		stock, err := client.Stock(ctx, "DUMMY_SYMBOL", Ascending)
 		require.NoError(t, err)
 		require.Len(t, stock.DailyData, 2)
 
 		t1, _ := time.Parse(time.DateOnly, "2019-09-20")
 		require.Equal(t, t1, stock.DailyData[0].Date)
 		require.Equal(t, 90.35, stock.DailyData[0].Close)
+		require.Equal(t, float64(199054), stock.DailyData[0].Volume)
 
 		t2, _ := time.Parse(time.DateOnly, "2019-09-13")
 		require.Equal(t, t2, stock.DailyData[1].Date)
@@ -84,20 +96,675 @@ func TestStock(t *testing.T) {
 	t.Run("Client request with a JSON error response", func(t *testing.T) {
 		resp = jsonErrResp
 		BaseURL = server.URL
-		client, err := NewAlphaVantageClient("DUMMY_API_KEY")
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
 		require.NoError(t, err)
 
-		_, err = client.Stock("DUMMY_SYMBOL", Ascending)
+		_, err = client.Stock(ctx, "DUMMY_SYMBOL", Ascending)
 		require.Error(t, err)
 	})
 
+	t.Run("Client request without a time series", func(t *testing.T) {
+		resp = `{"Time Series (Daily)": {}}`
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		_, err = client.Stock(ctx, "DUMMY_SYMBOL", Ascending)
+		require.ErrorContains(t, err, "no daily data returned for 'DUMMY_SYMBOL'")
+	})
+
 	t.Run("Client request with an invalid JSON response", func(t *testing.T) {
 		resp = invalidJson
 		BaseURL = server.URL
-		client, err := NewAlphaVantageClient("DUMMY_API_KEY")
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		_, err = client.Stock(ctx, "DUMMY_SYMBOL", Ascending)
+		require.Error(t, err)
+	})
+}
+
+func TestSearchSymbols(t *testing.T) {
+	ctx := context.Background()
+	successResp := `{
+		"bestMatches": [
+			{
+				"1. symbol": "TSCO.LON",
+				"2. name": "Tesco PLC",
+				"3. type": "Equity",
+				"4. region": "United Kingdom",
+				"5. marketOpen": "08:00",
+				"6. marketClose": "16:30",
+				"7. timezone": "UTC+01",
+				"8. currency": "GBX",
+				"9. matchScore": "0.7273"
+			},
+			{
+				"1. symbol": "TSCDY",
+				"2. name": "Tesco PLC",
+				"3. type": "Equity",
+				"4. region": "United States",
+				"5. marketOpen": "09:30",
+				"6. marketClose": "16:00",
+				"7. timezone": "UTC-04",
+				"8. currency": "USD",
+				"9. matchScore": "0.7143"
+			}
+		]
+	}`
+
+	jsonErrResp := `{
+		"Error Message": "Invalid API call. Please retry or visit the documentation (https://www.alphavantage.co/documentation/) for SYMBOL_SEARCH."
+	}`
+
+	resp := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.URL.Path, "/query")
+
+		params := r.URL.Query()
+		require.Equal(t, "SYMBOL_SEARCH", params.Get("function"))
+		require.Equal(t, "tesco plc", params.Get("keywords"))
+		require.Equal(t, "DUMMY_API_KEY", params.Get("apikey"))
+
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(resp))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	t.Run("Search with a success response", func(t *testing.T) {
+		resp = successResp
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		matches, err := client.SearchSymbols(ctx, "tesco plc")
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+		require.Equal(t, &SymbolMatch{
+			Symbol:     "TSCO.LON",
+			Name:       "Tesco PLC",
+			Type:       "Equity",
+			Region:     "United Kingdom",
+			Currency:   "GBX",
+			MatchScore: 0.7273,
+		}, matches[0])
+		require.Equal(t, "TSCDY", matches[1].Symbol)
+	})
+
+	t.Run("Search with no matches", func(t *testing.T) {
+		resp = `{"bestMatches": []}`
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		matches, err := client.SearchSymbols(ctx, "tesco plc")
+		require.NoError(t, err)
+		require.Empty(t, matches)
+	})
+
+	t.Run("Search with a JSON error response", func(t *testing.T) {
+		resp = jsonErrResp
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		_, err = client.SearchSymbols(ctx, "tesco plc")
+		require.Error(t, err)
+	})
+}
+
+func TestQuote(t *testing.T) {
+	ctx := context.Background()
+	successResp := `{
+		"Global Quote": {
+			"01. symbol": "IBM",
+			"02. open": "231.2000",
+			"03. high": "233.0000",
+			"04. low": "230.4100",
+			"05. price": "232.2000",
+			"06. volume": "4328497",
+			"07. latest trading day": "2024-10-18",
+			"08. previous close": "232.5000",
+			"09. change": "-0.3000",
+			"10. change percent": "-0.1290%"
+		}
+	}`
+
+	unknownSymbolResp := `{
+		"Global Quote": {}
+	}`
+
+	resp := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.URL.Path, "/query")
+
+		params := r.URL.Query()
+		require.Equal(t, "GLOBAL_QUOTE", params.Get("function"))
+		require.Equal(t, "IBM", params.Get("symbol"))
+		require.Equal(t, "DUMMY_API_KEY", params.Get("apikey"))
+
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(resp))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	t.Run("Quote with a success response", func(t *testing.T) {
+		resp = successResp
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		quote, err := client.Quote(ctx, "IBM")
+		require.NoError(t, err)
+		require.Equal(t, &Quote{
+			Symbol:        "IBM",
+			Price:         232.2,
+			PreviousClose: 232.5,
+			Change:        -0.3,
+			ChangePercent: -0.129,
+			Volume:        4328497,
+			Timestamp:     time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC),
+		}, quote)
+	})
+
+	t.Run("Quote for an unknown symbol", func(t *testing.T) {
+		resp = unknownSymbolResp
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		_, err = client.Quote(ctx, "IBM")
+		require.Error(t, err)
+	})
+
+	t.Run("Quote with an invalid price", func(t *testing.T) {
+		resp = strings.Replace(successResp, "232.2000", "N/A", 1)
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		_, err = client.Quote(ctx, "IBM")
+		require.Error(t, err)
+	})
+}
+
+func TestFXDaily(t *testing.T) {
+	ctx := context.Background()
+	successResp := `{
+		"Meta Data": {
+			"1. Information": "Forex Daily Prices (open, high, low, close)",
+			"2. From Symbol": "USD",
+			"3. To Symbol": "EUR"
+		},
+		"Time Series FX (Daily)": {
+			"2024-10-17": {
+				"1. open": "0.92100",
+				"2. high": "0.92600",
+				"3. low": "0.92000",
+				"4. close": "0.92450"
+			},
+			"2024-10-18": {
+				"1. open": "0.92450",
+				"2. high": "0.92500",
+				"3. low": "0.91900",
+				"4. close": "0.92080"
+			}
+		}
+	}`
+
+	jsonErrResp := `{
+		"Error Message": "Invalid API call. Please retry or visit the documentation (https://www.alphavantage.co/documentation/) for FX_DAILY."
+	}`
+
+	resp := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, r.URL.Path, "/query")
+
+		params := r.URL.Query()
+		require.Equal(t, "FX_DAILY", params.Get("function"))
+		require.Equal(t, "USD", params.Get("from_symbol"))
+		require.Equal(t, "EUR", params.Get("to_symbol"))
+		require.Equal(t, "DUMMY_API_KEY", params.Get("apikey"))
+
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(resp))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	t.Run("FX rates with a success response", func(t *testing.T) {
+		resp = successResp
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		rates, err := client.FXDaily(ctx, "USD", "EUR")
+		require.NoError(t, err)
+		require.Equal(t, []*FXRate{
+			{Date: time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC), Rate: 0.9208},
+			{Date: time.Date(2024, 10, 17, 0, 0, 0, 0, time.UTC), Rate: 0.9245},
+		}, rates)
+	})
+
+	t.Run("FX rates with an error response", func(t *testing.T) {
+		resp = jsonErrResp
+		BaseURL = server.URL
+		client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+		require.NoError(t, err)
+
+		_, err = client.FXDaily(ctx, "USD", "EUR")
+		require.Error(t, err)
+	})
+}
+
+func TestStockAssetClasses(t *testing.T) {
+	ctx := context.Background()
+	cryptoResp := `{
+		"Meta Data": {
+			"1. Information": "Daily Prices and Volumes for Digital Currency",
+			"2. Digital Currency Code": "BTC",
+			"4. Market Code": "EUR"
+		},
+		"Time Series (Digital Currency Daily)": {
+			"2024-10-19": {
+				"1. open": "62455.32000000",
+				"2. high": "62720.00000000",
+				"3. low": "62201.08000000",
+				"4. close": "62524.34000000",
+				"5. volume": "4.17592289"
+			},
+			"2024-10-18": {
+				"1. open": "61907.28000000",
+				"2. high": "63000.00000000",
+				"3. low": "61618.92000000",
+				"4. close": "62455.32000000",
+				"5. volume": "10.20561419"
+			}
+		}
+	}`
+
+	fxResp := `{
+		"Time Series FX (Daily)": {
+			"2024-10-18": {
+				"4. close": "1.08660"
+			}
+		}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		switch params.Get("function") {
+		case "TIME_SERIES_DAILY":
+			require.Equal(t, "^GSPC&outputsize=compact", params.Get("symbol"))
+			require.Equal(t, "full", params.Get("outputsize"))
+			_, _ = w.Write([]byte(`{"Time Series (Daily)": {"2024-10-18": {"4. close": "5864.67", "5. volume": "0"}}}`))
+		case "DIGITAL_CURRENCY_DAILY":
+			require.Equal(t, "BTC", params.Get("symbol"))
+			require.Equal(t, "EUR", params.Get("market"))
+			_, _ = w.Write([]byte(cryptoResp))
+		case "FX_DAILY":
+			require.Equal(t, "EUR", params.Get("from_symbol"))
+			require.Equal(t, "USD", params.Get("to_symbol"))
+			_, _ = w.Write([]byte(fxResp))
+		default:
+			t.Errorf("unexpected function %s", params.Get("function"))
+		}
+	}))
+	defer server.Close()
+	BaseURL = server.URL
+	client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+	require.NoError(t, err)
+
+	t.Run("Equity symbols are escaped", func(t *testing.T) {
+		stock, err := client.Stock(ctx, "^GSPC&outputsize=compact", Ascending)
 		require.NoError(t, err)
+		require.Equal(t, []*DayData{{Date: time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC), Close: 5864.67}}, stock.DailyData)
+	})
 
-		_, err = client.Stock("DUMMY_SYMBOL", Ascending)
+	t.Run("Crypto", func(t *testing.T) {
+		stock, err := client.Stock(ctx, "crypto:BTC-EUR", Ascending)
+		require.NoError(t, err)
+		require.Equal(t, []*DayData{
+			{Date: time.Date(2024, 10, 19, 0, 0, 0, 0, time.UTC), Close: 62524.34, Volume: 4.17592289},
+			{Date: time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC), Close: 62455.32, Volume: 10.20561419},
+		}, stock.DailyData)
+	})
+
+	t.Run("FX pair", func(t *testing.T) {
+		stock, err := client.Stock(ctx, "fx:EUR-USD", Ascending)
+		require.NoError(t, err)
+		require.Equal(t, []*DayData{{Date: time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC), Close: 1.0866}}, stock.DailyData)
+	})
+
+	t.Run("Invalid pair", func(t *testing.T) {
+		_, err := client.Stock(ctx, "crypto:BTC", Ascending)
+		require.Error(t, err)
+	})
+
+	t.Run("Unknown asset class", func(t *testing.T) {
+		_, err := client.Stock(ctx, "bond:US10Y", Ascending)
 		require.Error(t, err)
 	})
+
+	t.Run("Quotes are only supported for equities", func(t *testing.T) {
+		_, err := client.Quote(ctx, "crypto:BTC-EUR")
+		require.ErrorIs(t, err, ErrUnsupportedAssetClass)
+	})
+}
+
+func TestAdjustedStock(t *testing.T) {
+	ctx := context.Background()
+	successResp := `{
+		"Meta Data": {
+			"1. Information": "Daily Time Series with Splits and Dividend Events",
+			"2. Symbol": "NVDA"
+		},
+		"Time Series (Daily)": {
+			"2024-06-10": {
+				"1. open": "120.3700",
+				"2. high": "123.1000",
+				"3. low": "117.0100",
+				"4. close": "121.7900",
+				"5. adjusted close": "121.7653",
+				"6. volume": "314162712",
+				"7. dividend amount": "0.0000",
+				"8. split coefficient": "10.0"
+			},
+			"2024-06-07": {
+				"1. open": "1197.7000",
+				"2. high": "1216.9171",
+				"3. low": "1180.2200",
+				"4. close": "1208.8800",
+				"5. adjusted close": "120.8635",
+				"6. volume": "41238580",
+				"7. dividend amount": "0.0000",
+				"8. split coefficient": "1.0"
+			},
+			"2024-06-11": {
+				"1. open": "121.7700",
+				"2. high": "122.8700",
+				"3. low": "118.7400",
+				"4. close": "120.9100",
+				"5. adjusted close": "120.8855",
+				"6. volume": "222551165",
+				"7. dividend amount": "0.0100",
+				"8. split coefficient": "1.0"
+			}
+		}
+	}`
+
+	resp := successResp
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		require.Equal(t, "TIME_SERIES_DAILY_ADJUSTED", params.Get("function"))
+		require.Equal(t, "NVDA", params.Get("symbol"))
+		require.Equal(t, "full", params.Get("outputsize"))
+		_, _ = w.Write([]byte(resp))
+	}))
+	defer server.Close()
+	BaseURL = server.URL
+	client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+	require.NoError(t, err)
+
+	t.Run("Adjusted closes with splits and dividends", func(t *testing.T) {
+		stock, err := client.AdjustedStock(ctx, "NVDA", Ascending)
+		require.NoError(t, err)
+		require.Equal(t, []*DayData{
+			{Date: time.Date(2024, 6, 11, 0, 0, 0, 0, time.UTC), Close: 120.91, AdjustedClose: 120.8855, Dividend: 0.01, Volume: 222551165},
+			{Date: time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), Close: 121.79, AdjustedClose: 121.7653, SplitCoefficient: 10, Volume: 314162712},
+			{Date: time.Date(2024, 6, 7, 0, 0, 0, 0, time.UTC), Close: 1208.88, AdjustedClose: 120.8635, Volume: 41238580},
+		}, stock.DailyData)
+	})
+
+	t.Run("Only equities can be adjusted", func(t *testing.T) {
+		_, err := client.AdjustedStock(ctx, "fx:EUR-USD", Ascending)
+		require.ErrorIs(t, err, ErrUnsupportedAssetClass)
+	})
+
+	t.Run("Premium endpoint notices are errors", func(t *testing.T) {
+		resp = `{"Information": "Thank you for using Alpha Vantage! This is a premium endpoint. You may subscribe to any of the premium plans at https://www.alphavantage.co/premium/ to instantly unlock all premium endpoints"}`
+		_, err := client.AdjustedStock(ctx, "NVDA", Ascending)
+		require.ErrorContains(t, err, "This is a premium endpoint")
+	})
+
+	t.Run("Responses without a time series are errors", func(t *testing.T) {
+		resp = `{"Meta Data": {"2. Symbol": "NVDA"}, "Time Series (Daily)": {}}`
+		_, err := client.AdjustedStock(ctx, "NVDA", Ascending)
+		require.ErrorContains(t, err, "no adjusted daily data returned for 'NVDA'")
+	})
+}
+
+func TestOverview(t *testing.T) {
+	ctx := context.Background()
+	successResp := `{
+		"Symbol": "IBM",
+		"AssetType": "Common Stock",
+		"Name": "International Business Machines",
+		"Exchange": "NYSE",
+		"Currency": "USD",
+		"Sector": "TECHNOLOGY",
+		"Industry": "COMPUTER & OFFICE EQUIPMENT",
+		"MarketCapitalization": "212903313000",
+		"PERatio": "25.32",
+		"52WeekHigh": "237.37",
+		"52WeekLow": "142.58"
+	}`
+
+	resp := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		require.Equal(t, "OVERVIEW", params.Get("function"))
+		require.Equal(t, "IBM", params.Get("symbol"))
+		_, _ = w.Write([]byte(resp))
+	}))
+	defer server.Close()
+	BaseURL = server.URL
+	client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+	require.NoError(t, err)
+
+	t.Run("Overview with a success response", func(t *testing.T) {
+		resp = successResp
+		overview, err := client.Overview(ctx, "IBM")
+		require.NoError(t, err)
+		require.Equal(t, &Overview{
+			Symbol:     "IBM",
+			Name:       "International Business Machines",
+			Exchange:   "NYSE",
+			Currency:   "USD",
+			Sector:     "TECHNOLOGY",
+			Industry:   "COMPUTER & OFFICE EQUIPMENT",
+			MarketCap:  212903313000,
+			PERatio:    25.32,
+			WeekHigh52: 237.37,
+			WeekLow52:  142.58,
+		}, overview)
+	})
+
+	t.Run("Overview with unknown values", func(t *testing.T) {
+		resp = strings.Replace(successResp, `"25.32"`, `"None"`, 1)
+		overview, err := client.Overview(ctx, "IBM")
+		require.NoError(t, err)
+		require.Zero(t, overview.PERatio)
+	})
+
+	t.Run("Overview with an invalid value", func(t *testing.T) {
+		resp = strings.Replace(successResp, `"212903313000"`, `"lots"`, 1)
+		_, err := client.Overview(ctx, "IBM")
+		require.Error(t, err)
+	})
+
+	t.Run("Overview for an unknown symbol", func(t *testing.T) {
+		resp = `{}`
+		_, err := client.Overview(ctx, "IBM")
+		require.Error(t, err)
+	})
+
+	t.Run("Overviews are only supported for equities", func(t *testing.T) {
+		_, err := client.Overview(ctx, "crypto:BTC-USD")
+		require.ErrorIs(t, err, ErrUnsupportedAssetClass)
+	})
+}
+
+func TestAPIKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	quoteResp := `{"Global Quote": {"01. symbol": "MSFT", "05. price": "418.1600", "06. volume": "17145300",
+		"07. latest trading day": "2024-10-18", "08. previous close": "416.7200", "09. change": "1.4400",
+		"10. change percent": "0.3456%"}}`
+	limited := map[string]bool{"KEY_1": true}
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("apikey")
+		keys = append(keys, key)
+		switch {
+		case key == "KEY_3":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case limited[key]:
+			_, _ = w.Write([]byte(`{"Information": "Thank you for using Alpha Vantage! Our standard API rate limit is 25 requests per day."}`))
+		default:
+			_, _ = w.Write([]byte(quoteResp))
+		}
+	}))
+	defer server.Close()
+	BaseURL = server.URL
+
+	client, err := NewAlphaVantageClient([]string{"KEY_1", "KEY_2"}, 0)
+	require.NoError(t, err)
+
+	t.Run("Rate limited keys are skipped", func(t *testing.T) {
+		_, err := client.Quote(ctx, "MSFT")
+		require.NoError(t, err)
+		_, err = client.Quote(ctx, "MSFT")
+		require.NoError(t, err)
+		require.Equal(t, []string{"KEY_1", "KEY_2", "KEY_2"}, keys)
+	})
+
+	t.Run("Every key is rate limited", func(t *testing.T) {
+		keys = nil
+		limited["KEY_2"] = true
+		_, err := client.Quote(ctx, "MSFT")
+		require.ErrorIs(t, err, ErrRateLimited)
+		require.Equal(t, []string{"KEY_2", "KEY_1"}, keys)
+	})
+
+	t.Run("Keys can be replaced", func(t *testing.T) {
+		keys = nil
+		require.NoError(t, client.SetAPIKeys([]string{"KEY_3", "KEY_4"}))
+		_, err := client.Quote(ctx, "MSFT")
+		require.NoError(t, err)
+		require.Equal(t, []string{"KEY_3", "KEY_4"}, keys)
+
+		require.Error(t, client.SetAPIKeys(nil))
+		require.Error(t, client.SetAPIKeys([]string{"KEY_5", " "}))
+	})
+
+	t.Run("Errors don't include keys", func(t *testing.T) {
+		server.Close()
+		_, err := client.Quote(ctx, "MSFT")
+		require.ErrorContains(t, err, "http request failed")
+		require.NotContains(t, err.Error(), "KEY_")
+	})
+}
+
+func TestKeyMetrics(t *testing.T) {
+	ctx := context.Background()
+	limited := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited[r.URL.Query().Get("apikey")] {
+			_, _ = w.Write([]byte(`{"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 25 requests per day."}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Global Quote": {"01. symbol": "MSFT", "05. price": "418.1600", "06. volume": "17145300",
+			"07. latest trading day": "2024-10-18", "08. previous close": "416.7200", "09. change": "1.4400",
+			"10. change percent": "0.3456%"}}`))
+	}))
+	defer server.Close()
+	BaseURL = server.URL
+
+	client, err := NewAlphaVantageClient([]string{"KEY_1", "KEY_2"}, 3)
+	require.NoError(t, err)
+	now := time.Date(2024, 10, 18, 23, 0, 0, 0, time.UTC)
+	client.apiKeys.now = func() time.Time { return now }
+
+	requireMetrics := func(t *testing.T, remaining1, remaining2, rateLimited1 int) {
+		expected := fmt.Sprintf(`# HELP stockticker_stock_controller_upstream_quota_remaining Number of requests each API key has left today, UTC. Only reported if the daily quota is set
+# TYPE stockticker_stock_controller_upstream_quota_remaining gauge
+stockticker_stock_controller_upstream_quota_remaining{key="%[1]s"} %[3]d
+stockticker_stock_controller_upstream_quota_remaining{key="%[2]s"} %[4]d
+# HELP stockticker_stock_controller_upstream_rate_limited_total Number of responses rate limiting each API key
+# TYPE stockticker_stock_controller_upstream_rate_limited_total counter
+stockticker_stock_controller_upstream_rate_limited_total{key="%[1]s"} %[5]d
+stockticker_stock_controller_upstream_rate_limited_total{key="%[2]s"} 0
+`, keyFingerprint("KEY_1"), keyFingerprint("KEY_2"), remaining1, remaining2, rateLimited1)
+		require.NoError(t, testutil.CollectAndCompare(client.KeyMetrics(), strings.NewReader(expected)))
+	}
+	requireMetrics(t, 3, 3, 0)
+
+	t.Run("Requests use up the quota", func(t *testing.T) {
+		_, err := client.Quote(ctx, "MSFT")
+		require.NoError(t, err)
+		requireMetrics(t, 2, 3, 0)
+	})
+
+	t.Run("Rate limited keys have no quota left", func(t *testing.T) {
+		limited["KEY_1"] = true
+		_, err := client.Quote(ctx, "MSFT")
+		require.NoError(t, err)
+		requireMetrics(t, 0, 2, 1)
+	})
+
+	t.Run("Quotas are reset daily", func(t *testing.T) {
+		now = now.Add(time.Hour)
+		requireMetrics(t, 3, 3, 1)
+	})
+
+	t.Run("Keys aren't exposed", func(t *testing.T) {
+		registry := prometheus.NewPedanticRegistry()
+		require.NoError(t, registry.Register(client.KeyMetrics()))
+		metrics, err := registry.Gather()
+		require.NoError(t, err)
+		require.NotContains(t, fmt.Sprint(metrics), "KEY_")
+	})
+
+	t.Run("The remaining quota isn't reported if the daily quota is unknown", func(t *testing.T) {
+		client, err := NewAlphaVantageClient([]string{"KEY_1"}, 0)
+		require.NoError(t, err)
+		require.Equal(t, 0, testutil.CollectAndCount(client.KeyMetrics(), "stockticker_stock_controller_upstream_quota_remaining"))
+		require.Equal(t, 1, testutil.CollectAndCount(client.KeyMetrics(), "stockticker_stock_controller_upstream_rate_limited_total"))
+	})
+}
+
+func TestTracing(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+		_, err := w.Write([]byte(`{"Global Quote": {}}`))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+	BaseURL = server.URL
+	client, err := NewAlphaVantageClient([]string{"DUMMY_API_KEY"}, 0)
+	require.NoError(t, err)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	_, _ = client.Quote(ctx, "IBM")
+	parent.End()
+
+	// The request has a client span but the trace isn't propagated to the third party
+	require.Empty(t, (<-headers).Get("traceparent"))
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "AlphaVantage GLOBAL_QUOTE", spans[0].Name())
+	require.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
 }
@@ -1,6 +1,7 @@
 package stockclient
 
 import (
+	"context"
 	"time"
 )
 
@@ -12,14 +13,76 @@ const (
 )
 
 type DayData struct {
-	Date  time.Time
-	Close float64
+	Date  time.Time `json:"date"`
+	Close float64   `json:"close"`
+	// Only set by AdjustedStock
+	AdjustedClose float64 `json:"adjustedClose,omitempty"`
+	Dividend      float64 `json:"dividend,omitempty"`
+	// Zero unless there was a split on the date, e.g. 2 for a 2 for 1 split
+	SplitCoefficient float64 `json:"splitCoefficient,omitempty"`
+	// Zero when unknown, e.g. for FX pairs. Fractional for cryptocurrencies
+	Volume float64 `json:"volume,omitempty"`
 }
 
 type Stock struct {
 	DailyData []*DayData
 }
 
+// SymbolMatch is a single result from a symbol search
+type SymbolMatch struct {
+	Symbol     string  `json:"symbol"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Region     string  `json:"region"`
+	Currency   string  `json:"currency"`
+	MatchScore float64 `json:"matchScore"`
+}
+
+// Quote is the latest price information for a symbol
+type Quote struct {
+	Symbol        string  `json:"symbol"`
+	Price         float64 `json:"price"`
+	PreviousClose float64 `json:"previousClose"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"changePercent"`
+	Volume        int64   `json:"volume"`
+	// Timestamp is the latest trading day the quote applies to
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Overview is basic information about a company
+type Overview struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Exchange string `json:"exchange"`
+	Currency string `json:"currency"`
+	Sector   string `json:"sector"`
+	Industry string `json:"industry"`
+	// Numeric fields are zero when unknown
+	MarketCap  int64   `json:"marketCap,omitempty"`
+	PERatio    float64 `json:"peRatio,omitempty"`
+	WeekHigh52 float64 `json:"52WeekHigh,omitempty"`
+	WeekLow52  float64 `json:"52WeekLow,omitempty"`
+}
+
+// FXRate is the closing exchange rate on a date
+type FXRate struct {
+	Date time.Time `json:"date"`
+	Rate float64   `json:"rate"`
+}
+
 type Client interface {
-	Stock(symbol string, sortOrder Order) (*Stock, error)
+	Stock(ctx context.Context, symbol string, sortOrder Order) (*Stock, error)
+	// AdjustedStock returns daily data including closes adjusted for splits and dividends. Only equities are supported
+	AdjustedStock(ctx context.Context, symbol string, sortOrder Order) (*Stock, error)
+	SearchSymbols(ctx context.Context, keywords string) ([]*SymbolMatch, error)
+	Quote(ctx context.Context, symbol string) (*Quote, error)
+	// Overview returns company information. Only equities are supported
+	Overview(ctx context.Context, symbol string) (*Overview, error)
+}
+
+// FXProvider provides daily exchange rates
+type FXProvider interface {
+	// FXDaily returns the daily rates for converting fromCurrency to toCurrency, newest first
+	FXDaily(ctx context.Context, fromCurrency, toCurrency string) ([]*FXRate, error)
 }
@@ -0,0 +1,180 @@
+package stockclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var ErrRateLimited = errors.New("rate limited by the stock data provider")
+
+// The key pool's metrics are named alongside the stock controller's, which cover the rest of fetching stock data.
+// Keys are labelled by a fingerprint so they aren't exposed
+var (
+	quotaRemainingDesc = prometheus.NewDesc(
+		"stockticker_stock_controller_upstream_quota_remaining",
+		"Number of requests each API key has left today, UTC. Only reported if the daily quota is set",
+		[]string{"key"}, nil,
+	)
+	rateLimitedDesc = prometheus.NewDesc(
+		"stockticker_stock_controller_upstream_rate_limited_total",
+		"Number of responses rate limiting each API key",
+		[]string{"key"}, nil,
+	)
+)
+
+// keyPool is a set of API keys. Requests use the current key until it's rate limited and then move on to the next
+type keyPool struct {
+	mu      sync.Mutex
+	keys    []string
+	current int
+	// The number of requests each key can make per day. 0 if unknown
+	dailyQuota int
+	usage      map[string]*keyUsage
+	now        func() time.Time
+}
+
+// keyUsage is what's known about a key's quota
+type keyUsage struct {
+	// The UTC date requests and limited are counted for, as quotas reset daily
+	day         string
+	requests    int
+	limited     bool
+	rateLimited int
+}
+
+func newKeyPool(keys []string, dailyQuota int) (*keyPool, error) {
+	if dailyQuota < 0 {
+		return nil, fmt.Errorf("the daily quota must not be negative")
+	}
+	pool := &keyPool{
+		dailyQuota: dailyQuota,
+		usage:      make(map[string]*keyUsage),
+		now:        time.Now,
+	}
+	if err := pool.set(keys); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// set replaces the keys, carrying on with the current key if it's still in the pool
+func (p *keyPool) set(keys []string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("at least one api key is required")
+	}
+	for _, key := range keys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("api keys must not be blank")
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := 0
+	if p.keys != nil {
+		current = max(slices.Index(keys, p.keys[p.current]), 0)
+	}
+	p.keys = keys
+	p.current = current
+
+	usage := make(map[string]*keyUsage, len(keys))
+	for _, key := range keys {
+		usage[key] = p.usage[key]
+		if usage[key] == nil {
+			usage[key] = &keyUsage{}
+		}
+	}
+	p.usage = usage
+	return nil
+}
+
+func (p *keyPool) get() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keys[p.current]
+}
+
+func (p *keyPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.keys)
+}
+
+// rotate moves on from key. Nothing changes if another request has already moved on from it
+func (p *keyPool) rotate(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.keys[p.current] == key {
+		p.current = (p.current + 1) % len(p.keys)
+	}
+}
+
+// record counts a request made with key towards its quota. Once it's rate limited its quota is used up for the day
+func (p *keyPool) record(key string, rateLimited bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	usage, ok := p.usage[key]
+	if !ok {
+		// The key was replaced while the request was in flight
+		return
+	}
+	usage.reset(p.today())
+	usage.requests++
+	if rateLimited {
+		usage.limited = true
+		usage.rateLimited++
+	}
+}
+
+func (p *keyPool) today() string {
+	return p.now().UTC().Format(time.DateOnly)
+}
+
+// reset clears the daily counts if they're for an earlier day
+func (u *keyUsage) reset(today string) {
+	if u.day != today {
+		u.day = today
+		u.requests = 0
+		u.limited = false
+	}
+}
+
+func (p *keyPool) Describe(ch chan<- *prometheus.Desc) {
+	ch <- quotaRemainingDesc
+	ch <- rateLimitedDesc
+}
+
+// Collect reports each key's usage. The remaining quota is worked out when it's collected so it's reset at midnight
+// even if no requests are made
+func (p *keyPool) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	today := p.today()
+	for key, usage := range p.usage {
+		usage.reset(today)
+		fingerprint := keyFingerprint(key)
+		ch <- prometheus.MustNewConstMetric(rateLimitedDesc, prometheus.CounterValue, float64(usage.rateLimited), fingerprint)
+		if p.dailyQuota == 0 {
+			continue
+		}
+		remaining := max(p.dailyQuota-usage.requests, 0)
+		if usage.limited {
+			remaining = 0
+		}
+		ch <- prometheus.MustNewConstMetric(quotaRemainingDesc, prometheus.GaugeValue, float64(remaining), fingerprint)
+	}
+}
+
+// keyFingerprint identifies key without revealing it
+func keyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,36 @@
+package stockclient
+
+import (
+	"errors"
+	"strings"
+)
+
+// AssetClass determines how a symbol's data is fetched. Symbols are prefixed with their asset class, e.g.
+// crypto:BTC-USD, apart from equities
+type AssetClass string
+
+const (
+	Equity AssetClass = "equity"
+	// Crypto symbols are a digital currency and the market it's priced in, e.g. crypto:BTC-USD
+	Crypto AssetClass = "crypto"
+	// FXPair symbols are a currency and the currency it's priced in, e.g. fx:EUR-USD
+	FXPair AssetClass = "fx"
+)
+
+var ErrUnsupportedAssetClass = errors.New("not supported for this asset class")
+
+// ParseSymbol splits symbol into its asset class and the symbol without its prefix. The asset class is returned as is
+// if it isn't recognised
+func ParseSymbol(symbol string) (AssetClass, string) {
+	prefix, rest, found := strings.Cut(symbol, ":")
+	if !found {
+		return Equity, symbol
+	}
+	return AssetClass(strings.ToLower(prefix)), rest
+}
+
+// SplitPair splits a crypto or FX pair such as BTC-USD into its base and quote currencies
+func SplitPair(pair string) (string, string, bool) {
+	base, quote, found := strings.Cut(pair, "-")
+	return base, quote, found && base != "" && quote != ""
+}